@@ -5,12 +5,441 @@ package main
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mikesep/frond/internal/action"
+	"github.com/mikesep/frond/internal/git"
 )
 
 type branchOptions struct {
+	Create branchCreateOptions `command:"create" description:"Create a branch in each repo and switch to it."`
+	Switch branchSwitchOptions `command:"switch" description:"Switch to a branch in each repo."`
+	Delete branchDeleteOptions `command:"delete" description:"Delete a branch in each repo."`
+	List   branchListOptions   `command:"list" alias:"ls" description:"List the branches in each repo."`
+}
+
+type branchActionOptions struct {
+	repoSelectionOptions
+	parallelOptions
+
+	DryRun bool `short:"n" long:"dry-run" description:"Print actions instead of doing them."`
+}
+
+type branchArgs struct {
+	Branch string   `positional-arg-name:"branch" required:"yes"`
+	Paths  []string `positional-arg-name:"paths"`
+}
+
+//------------------------------------------------------------------------------
+
+type branchCreateOptions struct {
+	branchActionOptions
+
+	StartPoint string `long:"start-point" value-name:"REF" description:"Start the branch at REF instead of HEAD."`
+	NoSwitch   bool   `long:"no-switch" description:"Create the branch without switching to it."`
+
+	Args branchArgs `positional-args:"yes" required:"yes"`
+}
+
+func (opts *branchCreateOptions) Execute(args []string) error {
+	repos, err := opts.selectRepos(append(opts.Args.Paths, args...))
+	if err != nil {
+		return err
+	}
+
+	actions := make([]action.Action, 0, len(repos))
+	for _, r := range repos {
+		actions = append(actions, actionCreateBranch{
+			Path:       r,
+			Branch:     opts.Args.Branch,
+			StartPoint: opts.StartPoint,
+			Switch:     !opts.NoSwitch,
+			DryRun:     opts.DryRun,
+		})
+	}
+
+	return opts.runActions(actions, opts.DryRun)
+}
+
+type actionCreateBranch struct {
+	Path       string
+	Branch     string
+	StartPoint string
+	Switch     bool
+	DryRun     bool
+}
+
+func (a actionCreateBranch) Name() string {
+	return a.Path
+}
+
+func (a actionCreateBranch) Do() action.Event {
+	repo := git.LocalRepo{Root: a.Path}
+
+	branches, _, err := repo.LocalBranches()
+	if err != nil {
+		return action.Event{Type: action.Failed, Name: a.Path, Message: err.Error()}
+	}
+
+	if _, ok := branches[a.Branch]; ok {
+		return action.Event{
+			Type:    action.Ignored,
+			Name:    a.Path,
+			Message: fmt.Sprintf("%q already exists", a.Branch),
+		}
+	}
+
+	if a.DryRun {
+		verb := "create"
+		if a.Switch {
+			verb = "create and switch to"
+		}
+		return action.Event{
+			Type:    action.Updated,
+			Name:    a.Path,
+			Message: fmt.Sprintf("would %s %q", verb, a.Branch),
+		}
+	}
+
+	if err := repo.CreateBranch(a.Branch, a.StartPoint); err != nil {
+		return action.Event{
+			Type:    action.Failed,
+			Name:    a.Path,
+			Message: fmt.Sprintf("failed to create %q: %v", a.Branch, err),
+		}
+	}
+
+	if !a.Switch {
+		return action.Event{
+			Type:    action.Updated,
+			Name:    a.Path,
+			Message: fmt.Sprintf("created %q", a.Branch),
+		}
+	}
+
+	if err := repo.SwitchToExistingBranch(a.Branch); err != nil {
+		return action.Event{
+			Type:    action.Failed,
+			Name:    a.Path,
+			Message: fmt.Sprintf("created %q but failed to switch to it: %v", a.Branch, err),
+		}
+	}
+
+	return action.Event{
+		Type:    action.Updated,
+		Name:    a.Path,
+		Message: fmt.Sprintf("created and switched to %q", a.Branch),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type branchSwitchOptions struct {
+	branchActionOptions
+
+	Args branchArgs `positional-args:"yes" required:"yes"`
+}
+
+func (opts *branchSwitchOptions) Execute(args []string) error {
+	repos, err := opts.selectRepos(append(opts.Args.Paths, args...))
+	if err != nil {
+		return err
+	}
+
+	actions := make([]action.Action, 0, len(repos))
+	for _, r := range repos {
+		actions = append(actions, actionSwitchBranch{
+			Path:   r,
+			Branch: opts.Args.Branch,
+			DryRun: opts.DryRun,
+		})
+	}
+
+	return opts.runActions(actions, opts.DryRun)
 }
 
-func (opts *branchOptions) Execute(args []string) error {
-	fmt.Printf("I'm branchOptions.Execute with args=%q, opts=%v\n", args, opts)
+type actionSwitchBranch struct {
+	Path   string
+	Branch string
+	DryRun bool
+}
+
+func (a actionSwitchBranch) Name() string {
+	return a.Path
+}
+
+func (a actionSwitchBranch) Do() action.Event {
+	failure := func(err error) action.Event {
+		return action.Event{Type: action.Failed, Name: a.Path, Message: err.Error()}
+	}
+
+	repo := git.LocalRepo{Root: a.Path}
+
+	branches, current, err := repo.LocalBranches()
+	if err != nil {
+		return failure(err)
+	}
+
+	if current == a.Branch {
+		return action.Event{
+			Type:    action.Unchanged,
+			Name:    a.Path,
+			Message: fmt.Sprintf("already on %q", a.Branch),
+		}
+	}
+
+	if _, ok := branches[a.Branch]; ok {
+		if a.DryRun {
+			return action.Event{
+				Type:    action.Updated,
+				Name:    a.Path,
+				Message: fmt.Sprintf("would switch to %q", a.Branch),
+			}
+		}
+
+		if err := repo.SwitchToExistingBranch(a.Branch); err != nil {
+			return failure(fmt.Errorf("failed to switch to %q: %w", a.Branch, err))
+		}
+
+		return action.Event{
+			Type:    action.Updated,
+			Name:    a.Path,
+			Message: fmt.Sprintf("switched to %q", a.Branch),
+		}
+	}
+
+	// No local branch, so look for exactly one remote branch to track.
+	upstream, err := findUniqueRemoteBranch(repo, a.Branch)
+	if err != nil {
+		return failure(err)
+	}
+	if upstream == "" {
+		return action.Event{
+			Type:    action.Ignored,
+			Name:    a.Path,
+			Message: fmt.Sprintf("no branch %q", a.Branch),
+		}
+	}
+
+	if a.DryRun {
+		return action.Event{
+			Type:    action.Updated,
+			Name:    a.Path,
+			Message: fmt.Sprintf("would switch to %q tracking %q", a.Branch, upstream),
+		}
+	}
+
+	if err := repo.SwitchToNewTrackingBranch(upstream); err != nil {
+		return failure(fmt.Errorf("failed to switch to %q tracking %q: %w", a.Branch, upstream, err))
+	}
+
+	return action.Event{
+		Type:    action.Updated,
+		Name:    a.Path,
+		Message: fmt.Sprintf("switched to %q tracking %q", a.Branch, upstream),
+	}
+}
+
+// findUniqueRemoteBranch returns "" unless exactly one remote has the branch.
+func findUniqueRemoteBranch(repo git.LocalRepo, branch string) (string, error) {
+	remotes, err := repo.Remotes()
+	if err != nil {
+		return "", err
+	}
+
+	var found []string
+	for name := range remotes {
+		exists, err := repo.RefExists("refs/remotes/" + name + "/" + branch)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			found = append(found, name+"/"+branch)
+		}
+	}
+
+	if len(found) != 1 {
+		return "", nil
+	}
+	return found[0], nil
+}
+
+//------------------------------------------------------------------------------
+
+type branchDeleteOptions struct {
+	branchActionOptions
+
+	Force bool `short:"f" long:"force" description:"Delete the branch even if it isn't merged."`
+
+	Args branchArgs `positional-args:"yes" required:"yes"`
+}
+
+func (opts *branchDeleteOptions) Execute(args []string) error {
+	repos, err := opts.selectRepos(append(opts.Args.Paths, args...))
+	if err != nil {
+		return err
+	}
+
+	actions := make([]action.Action, 0, len(repos))
+	for _, r := range repos {
+		actions = append(actions, actionDeleteBranch{
+			Path:   r,
+			Branch: opts.Args.Branch,
+			Force:  opts.Force,
+			DryRun: opts.DryRun,
+		})
+	}
+
+	return opts.runActions(actions, opts.DryRun)
+}
+
+type actionDeleteBranch struct {
+	Path   string
+	Branch string
+	Force  bool
+	DryRun bool
+}
+
+func (a actionDeleteBranch) Name() string {
+	return a.Path
+}
+
+func (a actionDeleteBranch) Do() action.Event {
+	repo := git.LocalRepo{Root: a.Path}
+
+	branches, current, err := repo.LocalBranches()
+	if err != nil {
+		return action.Event{Type: action.Failed, Name: a.Path, Message: err.Error()}
+	}
+
+	if _, ok := branches[a.Branch]; !ok {
+		return action.Event{
+			Type:    action.Ignored,
+			Name:    a.Path,
+			Message: fmt.Sprintf("no branch %q", a.Branch),
+		}
+	}
+
+	if current == a.Branch {
+		return action.Event{
+			Type:    action.Ignored,
+			Name:    a.Path,
+			Message: fmt.Sprintf("not deleting %q since it's the current branch", a.Branch),
+		}
+	}
+
+	if a.DryRun {
+		return action.Event{
+			Type:    action.Removed,
+			Name:    a.Path,
+			Message: fmt.Sprintf("would delete %q", a.Branch),
+		}
+	}
+
+	if err := repo.DeleteBranch(a.Branch, a.Force); err != nil {
+		msg := fmt.Sprintf("failed to delete %q: %v", a.Branch, err)
+		if !a.Force {
+			msg += " (not fully merged? use --force to delete it anyway)"
+		}
+		return action.Event{Type: action.Failed, Name: a.Path, Message: msg}
+	}
+
+	return action.Event{
+		Type:    action.Removed,
+		Name:    a.Path,
+		Message: fmt.Sprintf("deleted %q", a.Branch),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type branchListOptions struct {
+	repoSelectionOptions
+
+	NotDefault bool `long:"not-default" description:"Only list repos that aren't on their default branch."`
+}
+
+func (opts *branchListOptions) Execute(args []string) error {
+	repos, err := opts.selectRepos(args)
+	if err != nil {
+		return err
+	}
+
+	maxRepoLen := maxLength(repos)
+
+	for _, r := range repos {
+		names, ok, err := opts.listBranches(git.LocalRepo{Root: r})
+		if err != nil {
+			return fmt.Errorf("%s: %w", r, err)
+		}
+		if !ok {
+			continue
+		}
+
+		fmt.Printf("%-*s  %s\n", maxRepoLen, r, strings.Join(names, " "))
+	}
+
 	return nil
 }
+
+// listBranches returns the repo's branches in order, with the current one marked with a *. It
+// returns false if the repo shouldn't be listed.
+func (opts *branchListOptions) listBranches(repo git.LocalRepo) ([]string, bool, error) {
+	branches, current, err := repo.LocalBranches()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if opts.NotDefault {
+		defaultBranch, err := findDefaultBranch(repo)
+		if err != nil {
+			return nil, false, err
+		}
+		if defaultBranch != "" && current == defaultBranch {
+			return nil, false, nil
+		}
+	}
+
+	names := make([]string, 0, len(branches))
+	for name := range branches {
+		if name == current {
+			name = "*" + name
+		}
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return strings.TrimPrefix(names[i], "*") < strings.TrimPrefix(names[j], "*")
+	})
+
+	return names, true, nil
+}
+
+// findDefaultBranch prefers origin's default branch, then any other remote's. It returns ""
+// if none of the remotes know their default branch.
+func findDefaultBranch(repo git.LocalRepo) (string, error) {
+	remotes, err := repo.Remotes()
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(remotes))
+	for name := range remotes {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i] == "origin" || (names[j] != "origin" && names[i] < names[j])
+	})
+
+	for _, name := range names {
+		branch, err := repo.RemoteDefaultBranch(name)
+		if err != nil {
+			return "", err
+		}
+		if branch != "" {
+			return branch, nil
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,273 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bloomberg/go-testgroup"
+	"github.com/mikesep/frond/internal/action"
+	"github.com/mikesep/frond/internal/git"
+)
+
+func Test_Branch(t *testing.T) {
+	testgroup.RunInParallel(t, &branchTests{})
+}
+
+type branchTests struct{}
+
+// newClone returns the path to a clone of a new upstream repo with a commit on main, and an
+// upstream branch "remote-only" that the clone doesn't have a local branch for.
+func newClone(t *testgroup.T) (upstream, clone string) {
+	root := t.TempDir()
+	upstream = filepath.Join(root, "upstream")
+	clone = filepath.Join(root, "clone")
+
+	t.Require.NoError(os.MkdirAll(upstream, 0o755))
+	runGit(t, upstream, "init", "--quiet", "--initial-branch=main")
+	commitEmpty(t, upstream, "one")
+	runGit(t, upstream, "branch", "remote-only")
+	runGit(t, root, "clone", "--quiet", upstream, clone)
+
+	return upstream, clone
+}
+
+func runGit(t *testgroup.T, dir string, args ...string) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	t.Require.NoError(err, "git %s: %s", strings.Join(args, " "), out)
+}
+
+func commitEmpty(t *testgroup.T, dir, message string) {
+	runGit(t, dir, "-c", "user.name=frond", "-c", "user.email=frond@example.com",
+		"commit", "--quiet", "--allow-empty", "--message="+message)
+}
+
+func currentBranch(t *testgroup.T, dir string) string {
+	repo := git.LocalRepo{Root: dir}
+	current, err := repo.CurrentBranch()
+	t.Require.NoError(err)
+	return current
+}
+
+func hasBranch(t *testgroup.T, dir, branch string) bool {
+	repo := git.LocalRepo{Root: dir}
+	branches, _, err := repo.LocalBranches()
+	t.Require.NoError(err)
+	_, ok := branches[branch]
+	return ok
+}
+
+//------------------------------------------------------------------------------
+
+func (*branchTests) Create_and_switch(t *testgroup.T) {
+	_, clone := newClone(t)
+
+	event := actionCreateBranch{Path: clone, Branch: "feature", Switch: true}.Do()
+	t.Equal(action.Updated, event.Type, event.Message)
+	t.Equal(`created and switched to "feature"`, event.Message)
+	t.Equal("feature", currentBranch(t, clone))
+}
+
+func (*branchTests) Create_without_switching(t *testgroup.T) {
+	_, clone := newClone(t)
+
+	event := actionCreateBranch{Path: clone, Branch: "feature"}.Do()
+	t.Equal(action.Updated, event.Type, event.Message)
+	t.Equal(`created "feature"`, event.Message)
+	t.True(hasBranch(t, clone, "feature"))
+	t.Equal("main", currentBranch(t, clone))
+}
+
+func (*branchTests) Create_dry_run(t *testgroup.T) {
+	_, clone := newClone(t)
+
+	event := actionCreateBranch{Path: clone, Branch: "feature", Switch: true, DryRun: true}.Do()
+	t.Equal(action.Updated, event.Type, event.Message)
+	t.Equal(`would create and switch to "feature"`, event.Message)
+	t.False(hasBranch(t, clone, "feature"))
+}
+
+func (*branchTests) Create_when_it_already_exists(t *testgroup.T) {
+	_, clone := newClone(t)
+	runGit(t, clone, "branch", "feature")
+
+	event := actionCreateBranch{Path: clone, Branch: "feature", Switch: true}.Do()
+	t.Equal(action.Ignored, event.Type, event.Message)
+	t.Equal(`"feature" already exists`, event.Message)
+	t.Equal("main", currentBranch(t, clone))
+}
+
+func (*branchTests) Create_from_a_bad_start_point(t *testgroup.T) {
+	_, clone := newClone(t)
+
+	event := actionCreateBranch{Path: clone, Branch: "feature", StartPoint: "nope"}.Do()
+	t.Equal(action.Failed, event.Type, event.Message)
+	t.False(hasBranch(t, clone, "feature"))
+}
+
+//------------------------------------------------------------------------------
+
+func (*branchTests) Switch_to_a_local_branch(t *testgroup.T) {
+	_, clone := newClone(t)
+	runGit(t, clone, "branch", "feature")
+
+	event := actionSwitchBranch{Path: clone, Branch: "feature"}.Do()
+	t.Equal(action.Updated, event.Type, event.Message)
+	t.Equal(`switched to "feature"`, event.Message)
+	t.Equal("feature", currentBranch(t, clone))
+}
+
+func (*branchTests) Switch_to_a_remote_branch(t *testgroup.T) {
+	_, clone := newClone(t)
+
+	event := actionSwitchBranch{Path: clone, Branch: "remote-only"}.Do()
+	t.Equal(action.Updated, event.Type, event.Message)
+	t.Equal(`switched to "remote-only" tracking "origin/remote-only"`, event.Message)
+	t.Equal("remote-only", currentBranch(t, clone))
+}
+
+func (*branchTests) Switch_dry_run(t *testgroup.T) {
+	_, clone := newClone(t)
+
+	event := actionSwitchBranch{Path: clone, Branch: "remote-only", DryRun: true}.Do()
+	t.Equal(action.Updated, event.Type, event.Message)
+	t.Equal(`would switch to "remote-only" tracking "origin/remote-only"`, event.Message)
+	t.Equal("main", currentBranch(t, clone))
+}
+
+func (*branchTests) Switch_to_the_current_branch(t *testgroup.T) {
+	_, clone := newClone(t)
+
+	event := actionSwitchBranch{Path: clone, Branch: "main"}.Do()
+	t.Equal(action.Unchanged, event.Type, event.Message)
+	t.Equal(`already on "main"`, event.Message)
+}
+
+func (*branchTests) Switch_to_a_missing_branch(t *testgroup.T) {
+	_, clone := newClone(t)
+
+	event := actionSwitchBranch{Path: clone, Branch: "nope"}.Do()
+	t.Equal(action.Ignored, event.Type, event.Message)
+	t.Equal(`no branch "nope"`, event.Message)
+	t.Equal("main", currentBranch(t, clone))
+}
+
+func (*branchTests) Switch_when_several_remotes_have_the_branch(t *testgroup.T) {
+	upstream, clone := newClone(t)
+	runGit(t, clone, "remote", "add", "other", upstream)
+	runGit(t, clone, "fetch", "--quiet", "other")
+
+	event := actionSwitchBranch{Path: clone, Branch: "remote-only"}.Do()
+	t.Equal(action.Ignored, event.Type, event.Message)
+	t.Equal(`no branch "remote-only"`, event.Message)
+}
+
+//------------------------------------------------------------------------------
+
+func (*branchTests) Delete_a_merged_branch(t *testgroup.T) {
+	_, clone := newClone(t)
+	runGit(t, clone, "branch", "feature")
+
+	event := actionDeleteBranch{Path: clone, Branch: "feature"}.Do()
+	t.Equal(action.Removed, event.Type, event.Message)
+	t.Equal(`deleted "feature"`, event.Message)
+	t.False(hasBranch(t, clone, "feature"))
+}
+
+func (*branchTests) Delete_dry_run(t *testgroup.T) {
+	_, clone := newClone(t)
+	runGit(t, clone, "branch", "feature")
+
+	event := actionDeleteBranch{Path: clone, Branch: "feature", DryRun: true}.Do()
+	t.Equal(action.Removed, event.Type, event.Message)
+	t.Equal(`would delete "feature"`, event.Message)
+	t.True(hasBranch(t, clone, "feature"))
+}
+
+func (*branchTests) Delete_an_unmerged_branch(t *testgroup.T) {
+	_, clone := newClone(t)
+	runGit(t, clone, "switch", "--quiet", "--create", "feature")
+	commitEmpty(t, clone, "local work")
+	runGit(t, clone, "switch", "--quiet", "main")
+
+	event := actionDeleteBranch{Path: clone, Branch: "feature"}.Do()
+	t.Equal(action.Failed, event.Type, event.Message)
+	t.Contains(event.Message, "use --force to delete it anyway")
+	t.True(hasBranch(t, clone, "feature"))
+
+	event = actionDeleteBranch{Path: clone, Branch: "feature", Force: true}.Do()
+	t.Equal(action.Removed, event.Type, event.Message)
+	t.False(hasBranch(t, clone, "feature"))
+}
+
+func (*branchTests) Delete_a_missing_branch(t *testgroup.T) {
+	_, clone := newClone(t)
+
+	event := actionDeleteBranch{Path: clone, Branch: "nope"}.Do()
+	t.Equal(action.Ignored, event.Type, event.Message)
+	t.Equal(`no branch "nope"`, event.Message)
+}
+
+func (*branchTests) Delete_the_current_branch(t *testgroup.T) {
+	_, clone := newClone(t)
+
+	event := actionDeleteBranch{Path: clone, Branch: "main", Force: true}.Do()
+	t.Equal(action.Ignored, event.Type, event.Message)
+	t.Equal(`not deleting "main" since it's the current branch`, event.Message)
+	t.True(hasBranch(t, clone, "main"))
+}
+
+//------------------------------------------------------------------------------
+
+func (*branchTests) List_marks_the_current_branch(t *testgroup.T) {
+	_, clone := newClone(t)
+	runGit(t, clone, "branch", "zebra")
+	runGit(t, clone, "switch", "--quiet", "--create", "feature")
+
+	opts := branchListOptions{}
+	names, ok, err := opts.listBranches(git.LocalRepo{Root: clone})
+	t.Require.NoError(err)
+	t.True(ok)
+	t.Equal([]string{"*feature", "main", "zebra"}, names)
+}
+
+func (*branchTests) List_only_repos_not_on_their_default_branch(t *testgroup.T) {
+	_, clone := newClone(t)
+	repo := git.LocalRepo{Root: clone}
+
+	opts := branchListOptions{NotDefault: true}
+	_, ok, err := opts.listBranches(repo)
+	t.Require.NoError(err)
+	t.False(ok, "on the default branch")
+
+	runGit(t, clone, "switch", "--quiet", "--create", "feature")
+	names, ok, err := opts.listBranches(repo)
+	t.Require.NoError(err)
+	t.True(ok)
+	t.Equal([]string{"*feature", "main"}, names)
+}
+
+func (*branchTests) Default_branch_prefers_origin(t *testgroup.T) {
+	upstream, clone := newClone(t)
+	repo := git.LocalRepo{Root: clone}
+
+	runGit(t, clone, "remote", "add", "aardvark", upstream)
+	runGit(t, clone, "fetch", "--quiet", "aardvark")
+	runGit(t, clone, "remote", "set-head", "aardvark", "remote-only")
+
+	branch, err := findDefaultBranch(repo)
+	t.Require.NoError(err)
+	t.Equal("main", branch)
+
+	runGit(t, clone, "remote", "set-head", "--delete", "origin")
+	branch, err = findDefaultBranch(repo)
+	t.Require.NoError(err)
+	t.Equal("remote-only", branch)
+}
@@ -0,0 +1,163 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+
+	"github.com/mikesep/frond/internal/action"
+)
+
+type doOptions struct {
+	repoSelectionOptions
+	parallelOptions
+
+	command []string // split off by run before parsing, since the parser swallows "--"
+}
+
+func (opts *doOptions) Usage() string {
+	return "[OPTIONS] [paths...] -- command [args...]"
+}
+
+func (opts *doOptions) Execute(paths []string) error {
+	command := opts.command
+	if len(command) == 0 {
+		return fmt.Errorf("missing command (expected [paths...] -- command [args...])")
+	}
+
+	repos, err := opts.selectRepos(paths)
+	if err != nil {
+		return err
+	}
+
+	results := &doResults{outputs: map[string][]byte{}, exitCodes: map[string]int{}}
+
+	actions := make([]action.Action, 0, len(repos))
+	for _, r := range repos {
+		actions = append(actions, actionRunCommand{Path: r, Command: command, Results: results})
+	}
+
+	err = opts.runActions(actions, false)
+
+	results.print(repos)
+
+	return err
+}
+
+// splitPathsAndCommand splits the raw args at the first "--". Everything before it, including
+// options and paths, is for frond, and everything after it is the command. Without a "--",
+// there's no command.
+func splitPathsAndCommand(args []string) (frondArgs, command []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i], args[i+1:]
+		}
+	}
+
+	return args, nil
+}
+
+//------------------------------------------------------------------------------
+
+type actionRunCommand struct {
+	Path    string
+	Command []string
+	Results *doResults
+}
+
+func (a actionRunCommand) Name() string {
+	return a.Path
+}
+
+func (a actionRunCommand) Do() action.Event {
+	cmd := exec.Command(a.Command[0], a.Command[1:]...)
+	cmd.Dir = a.Path
+
+	out, err := cmd.CombinedOutput()
+
+	exitCode := 0
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			a.Results.add(a.Path, out, -1)
+			return action.Event{Type: action.Failed, Name: a.Path, Message: err.Error()}
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	a.Results.add(a.Path, out, exitCode)
+
+	if exitCode != 0 {
+		return action.Event{Type: action.Failed, Name: a.Path, Message: err.Error()}
+	}
+
+	return action.Event{Type: action.Succeeded, Name: a.Path, Message: "exit status 0"}
+}
+
+//------------------------------------------------------------------------------
+
+type doResults struct {
+	mu        sync.Mutex
+	outputs   map[string][]byte // repo -> combined stdout and stderr
+	exitCodes map[string]int    // repo -> exit code (-1 if the command didn't run)
+}
+
+func (r *doResults) add(repo string, output []byte, exitCode int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.outputs[repo] = output
+	r.exitCodes[repo] = exitCode
+}
+
+func (r *doResults) print(repos []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, repo := range repos {
+		out := r.outputs[repo]
+		if len(out) == 0 {
+			continue
+		}
+
+		fmt.Printf("\n==> %s <==\n", repo)
+		os.Stdout.Write(out)
+		if !bytes.HasSuffix(out, []byte("\n")) {
+			fmt.Println()
+		}
+	}
+
+	reposByCode := map[int]int{}
+	for _, code := range r.exitCodes {
+		reposByCode[code]++
+	}
+
+	codes := make([]int, 0, len(reposByCode))
+	for code := range reposByCode {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	if len(codes) > 0 {
+		fmt.Printf("\nExit codes:\n")
+	}
+	for _, code := range codes {
+		repoWord := "repos"
+		if reposByCode[code] == 1 {
+			repoWord = "repo"
+		}
+
+		if code == -1 {
+			fmt.Printf("  did not run: %d %s\n", reposByCode[code], repoWord)
+			continue
+		}
+		fmt.Printf("  %3d: %d %s\n", code, reposByCode[code], repoWord)
+	}
+}
@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"testing"
+
+	"github.com/bloomberg/go-testgroup"
+)
+
+func Test_Do(t *testing.T) {
+	testgroup.RunInParallel(t, &doTests{})
+}
+
+type doTests struct{}
+
+func (*doTests) Split_at_the_first_separator(t *testgroup.T) {
+	testcases := []struct {
+		args      []string
+		frondArgs []string
+		command   []string
+	}{
+		{
+			args:      []string{"do", "--", "git", "pull"},
+			frondArgs: []string{"do"},
+			command:   []string{"git", "pull"},
+		},
+		{
+			args:      []string{"do", "--changed", "org", "--", "git", "diff", "--stat"},
+			frondArgs: []string{"do", "--changed", "org"},
+			command:   []string{"git", "diff", "--stat"},
+		},
+		{
+			args:      []string{"do", "--", "git", "log", "--", "README"},
+			frondArgs: []string{"do"},
+			command:   []string{"git", "log", "--", "README"},
+		},
+		{
+			// paths, not a command
+			args:      []string{"do", "git", "log", "--", "README"},
+			frondArgs: []string{"do", "git", "log"},
+			command:   []string{"README"},
+		},
+		{
+			args:      []string{"do", "git", "pull"},
+			frondArgs: []string{"do", "git", "pull"},
+		},
+		{
+			args:      []string{"do", "--"},
+			frondArgs: []string{"do"},
+			command:   []string{},
+		},
+	}
+
+	for _, tc := range testcases {
+		frondArgs, command := splitPathsAndCommand(tc.args)
+		t.Equal(tc.frondArgs, frondArgs, "%q", tc.args)
+		t.Equal(tc.command, command, "%q", tc.args)
+	}
+}
+
+func (*doTests) Missing_command_is_an_error(t *testgroup.T) {
+	opts := doOptions{}
+	t.EqualError(opts.Execute(nil), "missing command (expected [paths...] -- command [args...])")
+}
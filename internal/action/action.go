@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+// Package action runs per-repo actions in parallel and reports on their progress.
+package action
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+type Action interface {
+	Name() string
+	Do() Event
+}
+
+// Run does the actions with up to jobs workers and sends each resulting event to output.
+// Unless keepGoing is set, it stops handing out actions after the first failure. It returns
+// whether every action was handed out.
+func Run(actions []Action, jobs int, keepGoing bool, output Reporter) bool {
+	queue := make(chan Action)
+
+	var wg sync.WaitGroup
+	workerCtx, cancelWorkers := context.WithCancel(context.Background())
+	defer cancelWorkers()
+
+	wg.Add(jobs)
+	for i := 0; i < jobs; i++ {
+		go worker(workerCtx, cancelWorkers, &wg, queue, keepGoing, output)
+	}
+
+	enqueuedAll := enqueue(workerCtx, actions, queue)
+	close(queue)
+	wg.Wait()
+
+	return enqueuedAll
+}
+
+func worker(
+	ctx context.Context, cancelWorkers context.CancelFunc, wg *sync.WaitGroup,
+	queue <-chan Action, keepGoing bool, output Reporter,
+) {
+	defer wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done(): // cancelled
+			return
+
+		case a := <-queue:
+			if a == nil { // channel closed
+				return
+			}
+
+			event := a.Do()
+			output.HandleEvent(event)
+			if event.Type == Failed && !keepGoing {
+				cancelWorkers()
+				return
+			}
+		}
+	}
+}
+
+func enqueue(ctx context.Context, actions []Action, queue chan<- Action) bool {
+	enqueuedAll := false
+
+	for i, a := range actions {
+		select {
+		case <-ctx.Done():
+			return enqueuedAll
+		case queue <- a:
+			if i == len(actions)-1 {
+				enqueuedAll = true
+			}
+		}
+	}
+
+	return enqueuedAll
+}
+
+// NewReporter returns a serializing reporter for the actions that draws a progress bar when
+// out is a terminal, unless plain is set.
+func NewReporter(out *os.File, actions []Action, plain bool) Reporter {
+	maxNameLen := 0
+	for _, a := range actions {
+		if ln := len(a.Name()); ln > maxNameLen {
+			maxNameLen = ln
+		}
+	}
+
+	if term.IsTerminal(int(out.Fd())) && !plain {
+		return NewSerializingReporter(NewANSIReporter(out, len(actions), maxNameLen))
+	}
+
+	return NewSerializingReporter(NewPlainReporter(out, len(actions), maxNameLen))
+}
+
+//------------------------------------------------------------------------------
+
+// Jobs is the value of a --jobs option. Zero means runtime.NumCPU, and the option can't be set
+// to less than 1, since Run would then never finish.
+type Jobs int
+
+// UnmarshalFlag implements go-flags' Unmarshaler.
+func (j *Jobs) UnmarshalFlag(value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", value)
+	}
+	if n < 1 {
+		return fmt.Errorf("must be at least 1, not %d", n)
+	}
+	*j = Jobs(n)
+	return nil
+}
+
+func (j Jobs) workers() int {
+	if j < 1 {
+		return runtime.NumCPU()
+	}
+	return int(j)
+}
+
+// RunAndReport does the actions like Run, reporting on them to out like NewReporter, and returns
+// an error if any of them failed.
+func RunAndReport(out *os.File, actions []Action, jobs Jobs, keepGoing, plain bool) error {
+	output := NewReporter(out, actions, plain)
+	output.DrawInitial()
+
+	enqueuedAll := Run(actions, jobs.workers(), keepGoing, output)
+
+	var note string
+	if !enqueuedAll {
+		note = "Stopped early due to failures. (Use --keep-going to keep going.)"
+	}
+	output.Done(note)
+
+	if c := output.NumFailed(); c > 0 {
+		return fmt.Errorf("%d FAILED", c)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestJobs(t *testing.T) {
+	var j Jobs
+	if got := j.workers(); got != runtime.NumCPU() {
+		t.Errorf("unset workers = %d, want NumCPU", got)
+	}
+
+	if err := j.UnmarshalFlag("3"); err != nil || j.workers() != 3 {
+		t.Errorf("UnmarshalFlag(3) = %v, workers = %d", err, j.workers())
+	}
+
+	for _, bad := range []string{"0", "-1", "x"} {
+		if err := j.UnmarshalFlag(bad); err == nil {
+			t.Errorf("UnmarshalFlag(%q) succeeded", bad)
+		}
+	}
+}
+
+type fakeAction struct {
+	name      string
+	eventType EventType
+}
+
+func (a fakeAction) Name() string { return a.name }
+func (a fakeAction) Do() Event    { return Event{Type: a.eventType, Name: a.name} }
+
+func TestRunAndReport(t *testing.T) {
+	out, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	ok := []Action{fakeAction{"alice", Updated}, fakeAction{"bob", Unchanged}}
+	if err := RunAndReport(out, ok, 1, false, true); err != nil {
+		t.Errorf("RunAndReport = %v", err)
+	}
+
+	failing := append(ok, fakeAction{"crash", Failed}, fakeAction{"crash2", Failed})
+	err = RunAndReport(out, failing, 1, true, true)
+	if err == nil || err.Error() != "2 FAILED" {
+		t.Errorf("RunAndReport with --keep-going = %v, want 2 FAILED", err)
+	}
+
+	content, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "crash2") {
+		t.Errorf("output doesn't mention crash2:\n%s", content)
+	}
+}
@@ -1,7 +1,7 @@
 // SPDX-FileCopyrightText: 2021 Michael Seplowitz
 // SPDX-License-Identifier: MIT
 
-package sync
+package action
 
 import (
 	"fmt"
@@ -10,27 +10,28 @@ import (
 	"strings"
 )
 
-type actionEventType string
+type EventType string
 
 const (
-	actionCloned    actionEventType = "new "
-	actionFailed    actionEventType = "FAIL"
-	actionIgnored   actionEventType = "ign "
-	actionRemoved   actionEventType = "rm  "
-	actionUnchanged actionEventType = "ok  "
-	actionUpdated   actionEventType = "upd "
+	Cloned    EventType = "new "
+	Failed    EventType = "FAIL"
+	Ignored   EventType = "ign "
+	Removed   EventType = "rm  "
+	Succeeded EventType = "done"
+	Unchanged EventType = "ok  "
+	Updated   EventType = "upd "
 )
 
-type actionEvent struct {
-	Type    actionEventType
+type Event struct {
+	Type    EventType
 	Name    string
 	Message string
 	Caveats []string
 }
 
-type reporter interface {
+type Reporter interface {
 	DrawInitial()
-	HandleEvent(actionEvent)
+	HandleEvent(Event)
 	Done(note string)
 	NumFailed() int
 }
@@ -38,14 +39,14 @@ type reporter interface {
 //------------------------------------------------------------------------------
 
 type serializingReporter struct {
-	q    chan<- actionEvent
+	q    chan<- Event
 	done <-chan struct{}
 
-	next reporter
+	next Reporter
 }
 
-func newSerializingReporter(next reporter) *serializingReporter {
-	q := make(chan actionEvent)
+func NewSerializingReporter(next Reporter) *serializingReporter {
+	q := make(chan Event)
 	done := make(chan struct{})
 
 	go func() {
@@ -62,7 +63,7 @@ func (r *serializingReporter) DrawInitial() {
 	r.next.DrawInitial()
 }
 
-func (r *serializingReporter) HandleEvent(event actionEvent) {
+func (r *serializingReporter) HandleEvent(event Event) {
 	r.q <- event
 }
 
@@ -78,14 +79,14 @@ func (r *serializingReporter) NumFailed() int {
 
 //------------------------------------------------------------------------------
 
-func newANSIReporter(w io.Writer, totalItems int, maxNameLen int) *ansiReporter {
+func NewANSIReporter(w io.Writer, totalItems int, maxNameLen int) *ansiReporter {
 	r := &ansiReporter{
 		output:   w,
 		total:    totalItems,
 		countLen: len(strconv.Itoa(totalItems)),
 		nameLen:  maxNameLen,
-		failed:   make([]actionEvent, 0, totalItems),
-		ignored:  make([]actionEvent, 0, totalItems),
+		failed:   make([]Event, 0, totalItems),
+		ignored:  make([]Event, 0, totalItems),
 		caveats:  make(map[string][]string),
 	}
 
@@ -101,9 +102,10 @@ type ansiReporter struct {
 	done int
 
 	cloned    int
-	failed    []actionEvent
-	ignored   []actionEvent
+	failed    []Event
+	ignored   []Event
 	removed   int
+	succeeded int
 	unchanged int
 	updated   int
 
@@ -115,24 +117,26 @@ func (r *ansiReporter) DrawInitial() {
 	fmt.Fprintf(r.output, "\n") // leave space for event line
 }
 
-func (r *ansiReporter) HandleEvent(event actionEvent) {
+func (r *ansiReporter) HandleEvent(event Event) {
 	r.done++
 
 	fmt.Fprintf(r.output, "\x1b[2F") // up two lines
 	r.printProgressLine()
 
 	switch event.Type {
-	case actionCloned:
+	case Cloned:
 		r.cloned++
-	case actionFailed:
+	case Failed:
 		r.failed = append(r.failed, event)
-	case actionIgnored:
+	case Ignored:
 		r.ignored = append(r.ignored, event)
-	case actionRemoved:
+	case Removed:
 		r.removed++
-	case actionUnchanged:
+	case Succeeded:
+		r.succeeded++
+	case Unchanged:
 		r.unchanged++
-	case actionUpdated:
+	case Updated:
 		r.updated++
 	default:
 		panic(fmt.Sprintf("unexpected event: %#v", event))
@@ -167,6 +171,9 @@ func (r *ansiReporter) Done(note string) {
 	if r.removed > 0 {
 		fmt.Fprintf(r.output, "%d removed, ", r.removed)
 	}
+	if r.succeeded > 0 {
+		fmt.Fprintf(r.output, "%d succeeded, ", r.succeeded)
+	}
 	if r.unchanged > 0 {
 		fmt.Fprintf(r.output, "%d unchanged, ", r.unchanged)
 	}
@@ -196,11 +203,16 @@ func (r *ansiReporter) NumFailed() int {
 func (r *ansiReporter) printProgressLine() {
 	const barLen = 60
 
+	filled := barLen // nothing to do is all done
+	if r.total > 0 {
+		filled = barLen * r.done / r.total
+	}
+
 	fmt.Fprintf(r.output,
 		"%*d/%d [%-*s]",
 		r.countLen, r.done, r.total,
 		barLen,
-		strings.Repeat("=", int(barLen*r.done/r.total)),
+		strings.Repeat("=", filled),
 	)
 
 	fmt.Fprintf(r.output, "\x1b[0K\n") // clear from cursor to the end of the line, then \n
@@ -208,7 +220,7 @@ func (r *ansiReporter) printProgressLine() {
 
 //------------------------------------------------------------------------------
 
-func newPlainReporter(w io.Writer, totalItems int, maxNameLen int) *plainReporter {
+func NewPlainReporter(w io.Writer, totalItems int, maxNameLen int) *plainReporter {
 	return &plainReporter{
 		output:   w,
 		total:    totalItems,
@@ -227,6 +239,7 @@ type plainReporter struct {
 	failed    int
 	ignored   int
 	removed   int
+	succeeded int
 	unchanged int
 	updated   int
 
@@ -238,21 +251,23 @@ func (r *plainReporter) DrawInitial() {
 	// nothing required
 }
 
-func (r *plainReporter) HandleEvent(event actionEvent) {
+func (r *plainReporter) HandleEvent(event Event) {
 	r.done++
 
 	switch event.Type {
-	case actionCloned:
+	case Cloned:
 		r.cloned++
-	case actionFailed:
+	case Failed:
 		r.failed++
-	case actionIgnored:
+	case Ignored:
 		r.ignored++
-	case actionRemoved:
+	case Removed:
 		r.removed++
-	case actionUnchanged:
+	case Succeeded:
+		r.succeeded++
+	case Unchanged:
 		r.unchanged++
-	case actionUpdated:
+	case Updated:
 		r.updated++
 	default:
 		panic(fmt.Sprintf("unexpected action event: %#v", event))
@@ -288,6 +303,9 @@ func (r *plainReporter) Done(note string) {
 	if r.removed > 0 {
 		fmt.Fprintf(r.output, "%d removed, ", r.removed)
 	}
+	if r.succeeded > 0 {
+		fmt.Fprintf(r.output, "%d succeeded, ", r.succeeded)
+	}
 	if r.unchanged > 0 {
 		fmt.Fprintf(r.output, "%d unchanged, ", r.unchanged)
 	}
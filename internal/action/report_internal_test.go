@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package action
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlainOutputter(t *testing.T) {
+	r := NewPlainReporter(os.Stderr, 4, 5)
+
+	r.HandleEvent(Event{Type: Updated, Name: "alice"})
+	r.HandleEvent(Event{Type: Unchanged, Name: "bob"})
+	r.HandleEvent(Event{Type: Ignored, Name: "skip", Message: "it's in the name"})
+	r.HandleEvent(Event{Type: Failed, Name: "crash", Message: "oh no!"})
+
+	r.Done("")
+}
+
+func TestSerializedPlainReporter(t *testing.T) {
+	plain := NewPlainReporter(os.Stderr, 4, 5)
+
+	r := NewSerializingReporter(plain)
+
+	r.HandleEvent(Event{Type: Updated, Name: "alice"})
+	r.HandleEvent(Event{Type: Unchanged, Name: "bob"})
+	r.HandleEvent(Event{Type: Ignored, Name: "skip", Message: "it's in the name"})
+	r.HandleEvent(Event{Type: Failed, Name: "crash", Message: "oh no!"})
+
+	r.Done("")
+}
+
+func TestANSIReporterWithNothingToDo(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewANSIReporter(&buf, 0, 0)
+
+	r.DrawInitial()
+	r.Done("")
+
+	if !strings.Contains(buf.String(), "0 total") {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+}
+
+func TestSerializedANSIReporter(t *testing.T) {
+	events := []Event{
+		{Type: Updated, Name: "arlington"},
+		{Type: Updated, Name: "boston"},
+		{Type: Updated, Name: "chicago"},
+		{Type: Updated, Name: "dalles"},
+		{Type: Updated, Name: "encino"},
+		{Type: Updated, Name: "frankfort"},
+		{Type: Updated, Name: "georgetown"},
+		{Type: Updated, Name: "harrisburg"},
+		{Type: Updated, Name: "indianapolis"},
+		{Type: Updated, Name: "juneau"},
+		{Type: Updated, Name: "kalamazoo"},
+		{Type: Updated, Name: "louisville"},
+		{Type: Updated, Name: "minneapolis"},
+		{Type: Ignored, Name: "skip", Message: "it's in the name"},
+		{Type: Failed, Name: "crash", Message: "oh no!"},
+	}
+	maxNameLen := 0
+	for _, e := range events {
+		if len(e.Name) > maxNameLen {
+			maxNameLen = len(e.Name)
+		}
+	}
+
+	ansi := NewANSIReporter(os.Stderr, len(events), maxNameLen)
+
+	r := NewSerializingReporter(ansi)
+
+	delay := 100 * time.Millisecond
+	for _, e := range events {
+		time.Sleep(delay)
+		r.HandleEvent(e)
+	}
+
+	r.Done("")
+}
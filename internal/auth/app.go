@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/mikesep/frond/internal/github"
+)
+
+// GitHubApp uses an installation token for a GitHub App, which it gets by signing a JWT with the
+// app's private key.
+type GitHubApp struct {
+	Server         string
+	AppID          int64
+	InstallationID int64
+	PrivateKeyFile string
+
+	Client *http.Client // nil means http.DefaultClient
+}
+
+func (app GitHubApp) Token(ctx context.Context) (string, error) {
+	pemBytes, err := ioutil.ReadFile(app.PrivateKeyFile)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := github.ParsePrivateKey(pemBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", app.PrivateKeyFile, err)
+	}
+
+	jwt, err := github.AppJWT(app.AppID, key, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	sat := github.ServerAndToken{
+		Server: app.Server,
+		Token:  jwt,
+		Client: app.Client,
+	}
+
+	installationToken, err := sat.CreateInstallationToken(ctx, app.InstallationID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get a token for installation %d of app %d: %w",
+			app.InstallationID, app.AppID, err)
+	}
+
+	return installationToken.Token, nil
+}
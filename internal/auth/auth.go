@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+// Package auth gets API tokens from wherever the user keeps them.
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/mikesep/frond/internal/git"
+)
+
+// TokenSource provides a token for a server's API.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// GitCredential uses the password from `git credential fill`.
+type GitCredential struct {
+	Host string
+}
+
+func (gc GitCredential) Token(ctx context.Context) (string, error) {
+	cred, err := gc.Fill(ctx)
+	if err != nil {
+		return "", err
+	}
+	return cred.Password, nil
+}
+
+// Fill returns the whole credential, so that it can be approved or rejected later.
+func (gc GitCredential) Fill(ctx context.Context) (git.Credential, error) {
+	return git.FillCredential("https", gc.Host)
+}
+
+// FirstOf uses the first of the sources that has a token.
+type FirstOf []TokenSource
+
+func (f FirstOf) Token(ctx context.Context) (string, error) {
+	var errs []string
+	for _, source := range f {
+		token, err := source.Token(ctx)
+		if err == nil {
+			return token, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return "", fmt.Errorf("no token found (%s)", strings.Join(errs, "; "))
+}
+
+// Env uses the first of the environment variables that's set.
+type Env struct {
+	Vars []string
+}
+
+func (e Env) Token(ctx context.Context) (string, error) {
+	for _, v := range e.Vars {
+		if token := os.Getenv(v); token != "" {
+			return token, nil
+		}
+	}
+	return "", fmt.Errorf("none of %v are set", e.Vars)
+}
+
+// File uses the contents of a file, without surrounding whitespace.
+type File struct {
+	Path string
+}
+
+func (f File) Token(ctx context.Context) (string, error) {
+	data, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return "", err
+	}
+
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("%s is empty", f.Path)
+	}
+	return token, nil
+}
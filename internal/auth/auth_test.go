@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package auth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mikesep/frond/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Env(t *testing.T) {
+	t.Setenv("FROND_TEST_UNSET", "")
+	t.Setenv("FROND_TEST_TOKEN", "from-env")
+
+	token, err := auth.Env{Vars: []string{"FROND_TEST_UNSET", "FROND_TEST_TOKEN"}}.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", token)
+
+	_, err = auth.Env{Vars: []string{"FROND_TEST_UNSET"}}.Token(context.Background())
+	assert.EqualError(t, err, "none of [FROND_TEST_UNSET] are set")
+}
+
+func Test_FirstOf(t *testing.T) {
+	t.Setenv("FROND_TEST_UNSET", "")
+	t.Setenv("FROND_TEST_TOKEN", "from-env")
+
+	token, err := auth.FirstOf{
+		auth.Env{Vars: []string{"FROND_TEST_UNSET"}},
+		auth.Env{Vars: []string{"FROND_TEST_TOKEN"}},
+	}.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", token)
+
+	_, err = auth.FirstOf{
+		auth.Env{Vars: []string{"FROND_TEST_UNSET"}},
+		auth.File{Path: filepath.Join(t.TempDir(), "missing")},
+	}.Token(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "none of [FROND_TEST_UNSET] are set")
+	assert.Contains(t, err.Error(), "missing")
+}
+
+func Test_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, ioutil.WriteFile(path, []byte("from-file\n"), 0o600))
+
+	token, err := auth.File{Path: path}.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", token)
+}
+
+func Test_GHCLI(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "hosts.yml"), []byte(`
+github.com:
+    user: octocat
+    oauth_token: gho_fromgh
+    git_protocol: https
+ghes.example.com:
+    user: octocat
+    git_protocol: ssh
+`), 0o600))
+
+	token, err := auth.GHCLI{Host: "github.com", ConfigDir: dir}.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "gho_fromgh", token)
+
+	_, err = auth.GHCLI{Host: "ghes.example.com", ConfigDir: dir}.Token(context.Background())
+	assert.Contains(t, err.Error(), "keyring")
+
+	_, err = auth.GHCLI{Host: "other.example.com", ConfigDir: dir}.Token(context.Background())
+	assert.Contains(t, err.Error(), "gh auth login --hostname other.example.com")
+
+	t.Setenv("GH_CONFIG_DIR", dir)
+	token, err = auth.GHCLI{Host: "github.com"}.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "gho_fromgh", token)
+}
+
+func Test_GitHubApp(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	keyFile := filepath.Join(t.TempDir(), "app.pem")
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}), 0o600))
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v3/app/installations/42/access_tokens", r.URL.Path)
+
+		jwt := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		parts := strings.Split(jwt, ".")
+		require.Len(t, parts, 3)
+
+		sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+		require.NoError(t, err)
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig))
+
+		claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+		require.NoError(t, err)
+		var claims struct {
+			Iss int64 `json:"iss"`
+			Iat int64 `json:"iat"`
+			Exp int64 `json:"exp"`
+		}
+		require.NoError(t, json.Unmarshal(claimsJSON, &claims))
+		assert.Equal(t, int64(1234), claims.Iss)
+		assert.Equal(t, int64(600), claims.Exp-claims.Iat)
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"token": "ghs_installation", "expires_at": "2021-06-01T13:00:00Z"}`)
+	}))
+	defer ts.Close()
+
+	app := auth.GitHubApp{
+		Server:         strings.TrimPrefix(ts.URL, "https://"),
+		AppID:          1234,
+		InstallationID: 42,
+		PrivateKeyFile: keyFile,
+		Client:         ts.Client(),
+	}
+
+	token, err := app.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ghs_installation", token)
+}
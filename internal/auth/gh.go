@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// GHCLI uses the token that the gh CLI saved in its hosts.yml.
+type GHCLI struct {
+	Host      string
+	ConfigDir string // empty means where gh looks: $GH_CONFIG_DIR, $XDG_CONFIG_HOME/gh, ~/.config/gh
+}
+
+func (g GHCLI) Token(ctx context.Context) (string, error) {
+	dir := g.ConfigDir
+	if dir == "" {
+		var err error
+		dir, err = ghConfigDir()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	path := filepath.Join(dir, "hosts.yml")
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var hosts map[string]struct {
+		OAuthToken string `yaml:"oauth_token"`
+	}
+	if err := yaml.Unmarshal(data, &hosts); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	host, ok := hosts[g.Host]
+	if !ok {
+		return "", fmt.Errorf("%s isn't in %s (try gh auth login --hostname %s)", g.Host, path, g.Host)
+	}
+	if host.OAuthToken == "" {
+		return "", fmt.Errorf("%s has no token for %s (gh may keep it in the system keyring)",
+			path, g.Host)
+	}
+
+	return host.OAuthToken, nil
+}
+
+func ghConfigDir() (string, error) {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return dir, nil
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "gh"), nil
+}
@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+const pageLimit = 100
+
+var ErrNotFound = errors.New("not found")
+
+type Project struct {
+	Key    string `json:"key"`
+	Name   string `json:"name"`
+	Public bool   `json:"public"`
+}
+
+type Repo struct {
+	Slug    string   `json:"slug"`
+	Name    string   `json:"name"`
+	Project Project  `json:"project"`
+	Origin  *RepoRef `json:"origin"` // only set for forks
+	Links   Links    `json:"links"`
+
+	Archived bool `json:"archived"` // Bitbucket 8.0 and later
+	Public   bool `json:"public"`
+}
+
+type RepoRef struct {
+	Slug    string  `json:"slug"`
+	Project Project `json:"project"`
+}
+
+type Links struct {
+	Clone []Link `json:"clone"`
+}
+
+type Link struct {
+	Href string `json:"href"`
+	Name string `json:"name"` // http or ssh for clone links
+}
+
+// Fork is true when the API said which repo this one was forked from.
+func (r Repo) Fork() bool {
+	return r.Origin != nil
+}
+
+// CloneURL returns the clone link with the given name (http or ssh), or an empty string when
+// there isn't one.
+func (r Repo) CloneURL(name string) string {
+	for _, link := range r.Links.Clone {
+		if link.Name == name {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+func (sat ServerAndToken) GetProject(ctx context.Context, key string) (Project, error) {
+	var project Project
+	apiURL := fmt.Sprintf("%s/projects/%s", sat.restURL(), url.PathEscape(key))
+	err := sat.getJSON(ctx, apiURL, &project)
+	return project, err
+}
+
+func (sat ServerAndToken) GetRepo(ctx context.Context, projectKey, slug string) (Repo, error) {
+	var repo Repo
+	apiURL := fmt.Sprintf("%s/projects/%s/repos/%s",
+		sat.restURL(), url.PathEscape(projectKey), url.PathEscape(slug))
+	err := sat.getJSON(ctx, apiURL, &repo)
+	return repo, err
+}
+
+// ListProjectRepos pages through all of the repos in a project.
+func (sat ServerAndToken) ListProjectRepos(ctx context.Context, progress io.Writer, projectKey string,
+) ([]Repo, error) {
+	var results []Repo
+
+	baseURL := fmt.Sprintf("%s/projects/%s/repos?limit=%d",
+		sat.restURL(), url.PathEscape(projectKey), pageLimit)
+
+	start := 0
+
+	for {
+		fmt.Fprint(progress, ".") // print a dot for each iteration
+
+		var page struct {
+			Values        []Repo `json:"values"`
+			IsLastPage    bool   `json:"isLastPage"`
+			NextPageStart int    `json:"nextPageStart"`
+		}
+		if err := sat.getJSON(ctx, fmt.Sprintf("%s&start=%d", baseURL, start), &page); err != nil {
+			return results, err
+		}
+
+		results = append(results, page.Values...)
+
+		if page.IsLastPage || page.NextPageStart <= start {
+			return results, nil
+		}
+		start = page.NextPageStart
+	}
+}
+
+// DefaultBranch returns the short name of the repo's default branch, or an empty string when
+// the repo is empty.
+func (sat ServerAndToken) DefaultBranch(ctx context.Context, projectKey, slug string) (string, error) {
+	var branch struct {
+		DisplayID string `json:"displayId"`
+	}
+
+	apiURL := fmt.Sprintf("%s/projects/%s/repos/%s/default-branch",
+		sat.restURL(), url.PathEscape(projectKey), url.PathEscape(slug))
+	err := sat.getJSON(ctx, apiURL, &branch)
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+
+	return branch.DisplayID, err
+}
+
+//------------------------------------------------------------------------------
+
+func (sat ServerAndToken) getJSON(ctx context.Context, apiURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+sat.Token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := sat.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// yay!
+	case http.StatusNoContent:
+		return fmt.Errorf("%w: %s", ErrNotFound, apiURL) // e.g., no default branch yet
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrNotFound, apiURL)
+	default:
+		return fmt.Errorf("bad status code %d from %s", resp.StatusCode, apiURL)
+	}
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(respBytes, v)
+}
@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package bitbucket_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mikesep/frond/internal/bitbucket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const totalRepos = 250
+
+func newFakeServer(t *testing.T) bitbucket.ServerAndToken {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/rest/api/1.0/projects/TOOLS/repos", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		page := map[string]interface{}{"isLastPage": true}
+		var repos []bitbucket.Repo
+		for i := start; i < start+limit && i < totalRepos; i++ {
+			repos = append(repos, bitbucket.Repo{Slug: fmt.Sprintf("repo%03d", i)})
+		}
+		if start+limit < totalRepos {
+			page["isLastPage"] = false
+			page["nextPageStart"] = start + limit
+		}
+		page["values"] = repos
+
+		require.NoError(t, json.NewEncoder(w).Encode(page))
+	})
+
+	mux.HandleFunc("/rest/api/1.0/projects/TOOLS/repos/cli/default-branch",
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"id": "refs/heads/develop", "displayId": "develop"}`)
+		})
+	mux.HandleFunc("/rest/api/1.0/projects/TOOLS/repos/empty/default-branch",
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+	ts := httptest.NewTLSServer(mux)
+	t.Cleanup(ts.Close)
+
+	return bitbucket.ServerAndToken{
+		Server: strings.TrimPrefix(ts.URL, "https://"),
+		Token:  "secret",
+		Client: ts.Client(),
+	}
+}
+
+func Test_ListProjectRepos(t *testing.T) {
+	sat := newFakeServer(t)
+
+	var progress bytes.Buffer
+	repos, err := sat.ListProjectRepos(context.Background(), &progress, "TOOLS")
+	require.NoError(t, err)
+
+	assert.Equal(t, "...", progress.String())
+	require.Len(t, repos, totalRepos)
+	assert.Equal(t, "repo000", repos[0].Slug)
+	assert.Equal(t, "repo249", repos[totalRepos-1].Slug)
+}
+
+func Test_DefaultBranch(t *testing.T) {
+	sat := newFakeServer(t)
+	ctx := context.Background()
+
+	branch, err := sat.DefaultBranch(ctx, "TOOLS", "cli")
+	require.NoError(t, err)
+	assert.Equal(t, "develop", branch)
+
+	branch, err = sat.DefaultBranch(ctx, "TOOLS", "empty")
+	require.NoError(t, err)
+	assert.Equal(t, "", branch)
+}
+
+func Test_CloneURL(t *testing.T) {
+	var repo bitbucket.Repo
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"slug": "cli",
+		"links": {"clone": [
+			{"href": "ssh://git@bitbucket.example.com:7999/tools/cli.git", "name": "ssh"},
+			{"href": "https://bitbucket.example.com/scm/tools/cli.git", "name": "http"}
+		]}
+	}`), &repo))
+
+	assert.Equal(t, "https://bitbucket.example.com/scm/tools/cli.git", repo.CloneURL("http"))
+	assert.Equal(t, "ssh://git@bitbucket.example.com:7999/tools/cli.git", repo.CloneURL("ssh"))
+	assert.Equal(t, "", repo.CloneURL("ftp"))
+}
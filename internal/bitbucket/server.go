@@ -0,0 +1,43 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package bitbucket
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/mikesep/frond/internal/probe"
+)
+
+// ServerAndToken works with Bitbucket Server and Bitbucket Data Center.
+type ServerAndToken struct {
+	Server string // bitbucket.example.com
+	Token  string // an HTTP access token
+
+	Client *http.Client // nil means http.DefaultClient
+}
+
+func (sat *ServerAndToken) restURL() string {
+	return "https://" + sat.Server + "/rest/api/1.0"
+}
+
+func (sat *ServerAndToken) httpClient() *http.Client {
+	if sat.Client == nil {
+		return http.DefaultClient
+	}
+	return sat.Client
+}
+
+// DetectServer checks the application properties, which don't need authentication. A nil client
+// means http.DefaultClient.
+func DetectServer(ctx context.Context, client *http.Client, server string) bool {
+	sat := ServerAndToken{Server: server}
+	return probe.Check(ctx, client, http.MethodGet, sat.restURL()+"/application-properties",
+		func(resp *http.Response) bool {
+			var props struct {
+				DisplayName string `json:"displayName"`
+			}
+			return probe.DecodeJSON(resp, &props) && props.DisplayName == "Bitbucket"
+		})
+}
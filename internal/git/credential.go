@@ -22,12 +22,7 @@ type Credential struct {
 func FillCredential(protocol, host string) (Credential, error) {
 	var cred Credential
 
-	input := fmt.Sprintf("protocol=%s\nhost=%s\n\n", protocol, host)
-
-	cmd := exec.Command("git", "credential", "fill")
-	cmd.Stdin = strings.NewReader(input)
-
-	output, err := cmd.CombinedOutput()
+	output, err := runCredential("fill", Credential{Protocol: protocol, Host: host})
 	if err != nil {
 		return cred, err
 	}
@@ -51,3 +46,43 @@ func FillCredential(protocol, host string) (Credential, error) {
 
 	return cred, scanner.Err()
 }
+
+// ApproveCredential tells the credential helpers that cred worked, so they can store it.
+func ApproveCredential(cred Credential) error {
+	_, err := runCredential("approve", cred)
+	return err
+}
+
+// RejectCredential tells the credential helpers that cred didn't work, so they can forget it.
+func RejectCredential(cred Credential) error {
+	_, err := runCredential("reject", cred)
+	return err
+}
+
+func runCredential(action string, cred Credential) ([]byte, error) {
+	var input strings.Builder
+	for _, kv := range [][2]string{
+		{"protocol", cred.Protocol},
+		{"host", cred.Host},
+		{"username", cred.Username},
+		{"password", cred.Password},
+	} {
+		if kv[1] != "" {
+			fmt.Fprintf(&input, "%s=%s\n", kv[0], kv[1])
+		}
+	}
+	input.WriteString("\n")
+
+	cmd := exec.Command("git", "credential", action)
+	cmd.Stdin = strings.NewReader(input.String())
+	// Only ask the helpers, never the user. Callers fall back to other ways to get a token, and a
+	// prompt would be lost among the progress output anyway. (An empty GIT_ASKPASS also keeps
+	// core.askPass and SSH_ASKPASS from being used.)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=")
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return output, fmt.Errorf("git credential %s failed: %w", action, err)
+	}
+	return output, nil
+}
@@ -4,19 +4,84 @@
 package git_test
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/bloomberg/go-testgroup"
 	"github.com/mikesep/frond/internal/git"
 )
 
-func Test_Credential(t *testing.T) {
-	testgroup.RunInParallel(t, &CredentialTests{})
+func Test_CredentialHelper(t *testing.T) {
+	testgroup.RunSerially(t, &CredentialHelperTests{}) // the tests set environment variables
 }
 
-type CredentialTests struct{}
+type CredentialHelperTests struct {
+	log string
+}
+
+// PreTest replaces the user's credential helpers with a script that logs what it's asked to do.
+func (grp *CredentialHelperTests) PreTest(t *testgroup.T) {
+	dir := t.TempDir()
+	grp.log = filepath.Join(dir, "helper.log")
+
+	script := filepath.Join(dir, "helper")
+	t.Require.NoError(ioutil.WriteFile(script, []byte(`#!/bin/sh
+echo "action=$1" >> "$FROND_TEST_HELPER_LOG"
+cat >> "$FROND_TEST_HELPER_LOG"
+if [ "$1" = get ]; then
+	echo username=frond
+	echo password=s3cret
+fi
+`), 0o755))
+
+	t.Setenv("FROND_TEST_HELPER_LOG", grp.log)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "credential.helper")
+	t.Setenv("GIT_CONFIG_VALUE_0", script)
+}
+
+func (grp *CredentialHelperTests) helperLog(t *testgroup.T) string {
+	data, err := ioutil.ReadFile(grp.log)
+	t.Require.NoError(err)
+	return string(data)
+}
+
+func (grp *CredentialHelperTests) Fill(t *testgroup.T) {
+	cred, err := git.FillCredential("https", "example.com")
+	t.Require.NoError(err)
+	t.Equal(git.Credential{
+		Protocol: "https",
+		Host:     "example.com",
+		Username: "frond",
+		Password: "s3cret",
+	}, cred)
+
+	t.Equal("action=get\nprotocol=https\nhost=example.com\n", grp.helperLog(t))
+}
+
+func (grp *CredentialHelperTests) Fill_without_a_helper_does_not_prompt(t *testgroup.T) {
+	askpass := filepath.Join(t.TempDir(), "askpass")
+	t.Require.NoError(ioutil.WriteFile(askpass, []byte(`#!/bin/sh
+echo "askpass $1" >> "$FROND_TEST_HELPER_LOG"
+echo typed
+`), 0o755))
 
-func (*CredentialTests) Fill_for_github(t *testgroup.T) {
+	t.Setenv("GIT_CONFIG_COUNT", "0")
+	t.Setenv("GIT_ASKPASS", askpass)
+	t.Setenv("SSH_ASKPASS", askpass)
+
+	_, err := git.FillCredential("https", "example.com")
+	t.Error(err)
+
+	_, err = os.Stat(grp.log)
+	t.True(os.IsNotExist(err), "askpass was run")
+}
+
+func (grp *CredentialHelperTests) Fill_for_github(t *testgroup.T) {
 	cred, err := git.FillCredential("https", "github.com")
 
 	t.NoError(err)
@@ -25,3 +90,19 @@ func (*CredentialTests) Fill_for_github(t *testgroup.T) {
 	t.NotEmpty(cred.Username)
 	t.True(cred.Password != "", "cred.Password was empty") // use True to avoid exposing password
 }
+
+func (grp *CredentialHelperTests) Approve(t *testgroup.T) {
+	cred := git.Credential{Protocol: "https", Host: "example.com", Username: "frond", Password: "s3cret"}
+	t.Require.NoError(git.ApproveCredential(cred))
+
+	t.Equal("action=store\nprotocol=https\nhost=example.com\nusername=frond\npassword=s3cret\n",
+		grp.helperLog(t))
+}
+
+func (grp *CredentialHelperTests) Reject(t *testgroup.T) {
+	cred := git.Credential{Protocol: "https", Host: "example.com", Username: "frond", Password: "s3cret"}
+	t.Require.NoError(git.RejectCredential(cred))
+
+	t.Equal("action=erase\nprotocol=https\nhost=example.com\nusername=frond\npassword=s3cret\n",
+		grp.helperLog(t))
+}
@@ -4,57 +4,147 @@
 package git
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
 )
 
+// FindReposInDir returns the roots of the repositories in and under root, sorted. It doesn't
+// descend into repositories, so nested repositories (like submodules) aren't returned.
 func FindReposInDir(root string) ([]string, error) {
-	isRepoRoot, err := IsLocalRepoRoot(root)
-	if err != nil {
-		return nil, err
+	return findReposInDir(root, 4*runtime.NumCPU()) // mostly waiting on the filesystem
+}
+
+func findReposInDir(root string, workers int) ([]string, error) {
+	f := &repoFinder{
+		queue:   []string{root},
+		pending: 1,
 	}
-	if isRepoRoot {
-		return []string{root}, nil
+	f.cond = sync.NewCond(&f.mu)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			f.work()
+		}()
 	}
+	wg.Wait()
 
-	var repos []string
+	if f.err != nil {
+		return nil, f.err
+	}
 
-	dirQueue := []string{root}
+	sort.Strings(f.repos)
+	return f.repos, nil
+}
 
-	for len(dirQueue) > 0 {
-		dirPath := dirQueue[0]
-		dirQueue = dirQueue[1:]
+type repoFinder struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []string // dirs waiting to be examined
+	pending int      // dirs queued or being examined
+	repos   []string
+	err     error
+}
 
-		dir, err := os.Open(dirPath)
-		if err != nil {
-			return nil, fmt.Errorf("%s: %w", dirPath, err)
+func (f *repoFinder) work() {
+	for {
+		f.mu.Lock()
+		for len(f.queue) == 0 && f.pending > 0 && f.err == nil {
+			f.cond.Wait()
+		}
+		if f.pending == 0 || f.err != nil {
+			f.mu.Unlock()
+			return
 		}
+		dir := f.queue[len(f.queue)-1] // depth-first keeps the queue short
+		f.queue = f.queue[:len(f.queue)-1]
+		f.mu.Unlock()
+
+		isRepo, subdirs, err := examineDir(dir)
+
+		f.mu.Lock()
+		switch {
+		case err != nil:
+			if f.err == nil {
+				f.err = err
+			}
+		case isRepo:
+			f.repos = append(f.repos, dir)
+		default:
+			f.queue = append(f.queue, subdirs...)
+			f.pending += len(subdirs)
+		}
+		f.pending--
+		f.cond.Broadcast()
+		f.mu.Unlock()
+	}
+}
+
+// examineDir returns whether dir is a repo root, and if it isn't, the subdirs to examine next.
+func examineDir(dir string) (isRepo bool, subdirs []string, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, nil, fmt.Errorf("%s: %w", dir, err)
+	}
 
-		dirInfos, err := dir.Readdir(0)
+	for _, e := range entries {
+		if e.Name() != ".git" {
+			continue
+		}
+
+		isRepo, err := isRepoRootByDotGit(dir)
 		if err != nil {
-			return nil, fmt.Errorf("%s: %w", dirPath, err)
+			return false, nil, err
+		}
+		if isRepo {
+			return true, nil, nil
 		}
+		break
+	}
 
-		for _, fi := range dirInfos {
-			if !fi.IsDir() {
-				continue
-			}
+	// Symlinks aren't followed, since they can form cycles or lead outside of the root.
+	// (DirEntry.IsDir is false for a symlink to a directory.)
+	for _, e := range entries {
+		if e.IsDir() {
+			subdirs = append(subdirs, filepath.Join(dir, e.Name()))
+		}
+	}
 
-			path := filepath.Join(dirPath, fi.Name())
-			isRepoRoot, err := IsLocalRepoRoot(path)
-			if err != nil {
-				return nil, err
-			}
+	return false, subdirs, nil
+}
 
-			if !isRepoRoot {
-				dirQueue = append(dirQueue, path)
-				continue
-			}
+// isRepoRootByDotGit checks the .git entry in dir, which is a directory for regular repos and
+// a "gitdir: <path>" file for worktrees and submodules. Anything that doesn't look like either
+// is handed off to git itself.
+func isRepoRootByDotGit(dir string) (bool, error) {
+	dotGit := filepath.Join(dir, ".git")
+
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return IsLocalRepoRoot(dir)
+	}
 
-			repos = append(repos, path)
+	if info.IsDir() {
+		if _, err := os.Stat(filepath.Join(dotGit, "HEAD")); err == nil {
+			return true, nil
 		}
+		return IsLocalRepoRoot(dir)
+	}
+
+	content, err := os.ReadFile(dotGit)
+	if err != nil {
+		return false, err
+	}
+	if bytes.HasPrefix(content, []byte("gitdir: ")) {
+		return true, nil
 	}
 
-	return repos, nil
+	return IsLocalRepoRoot(dir)
 }
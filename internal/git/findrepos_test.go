@@ -0,0 +1,155 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package git_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/bloomberg/go-testgroup"
+	"github.com/mikesep/frond/internal/git"
+)
+
+func Test_FindReposInDir(t *testing.T) {
+	testgroup.RunInParallel(t, &FindReposTests{})
+}
+
+type FindReposTests struct{}
+
+func (*FindReposTests) Matches_rev_parse_implementation(t *testgroup.T) {
+	root := t.TempDir()
+
+	for _, dir := range []string{
+		"apache/airflow",
+		"apache/couchdb",
+		"bloomberg/deep/nested/repo",
+		"bloomberg/empty-dir/.keep",
+		"standalone",
+	} {
+		t.Require.NoError(os.MkdirAll(filepath.Join(root, dir), 0o755))
+	}
+
+	for _, repo := range []string{
+		"apache/airflow",
+		"apache/couchdb",
+		"bloomberg/deep/nested/repo",
+		"standalone",
+	} {
+		runGit(t, filepath.Join(root, repo), "init", "--quiet")
+	}
+
+	// a linked worktree has a .git file instead of a .git dir
+	commitEmpty(t, filepath.Join(root, "standalone"), "init")
+	runGit(t, filepath.Join(root, "standalone"),
+		"worktree", "add", "--quiet", filepath.Join(root, "worktrees", "standalone-wt"))
+
+	expected := findReposWithRevParse(t, root)
+
+	actual, err := git.FindReposInDir(root)
+	t.Require.NoError(err)
+
+	t.Equal(expected, actual)
+	t.Len(actual, 5)
+}
+
+func (*FindReposTests) Root_is_repo(t *testgroup.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "--quiet")
+	t.Require.NoError(os.MkdirAll(filepath.Join(root, "subdir"), 0o755))
+
+	actual, err := git.FindReposInDir(root)
+	t.Require.NoError(err)
+	t.Equal([]string{root}, actual)
+}
+
+func (*FindReposTests) Symlink_cycles_are_not_followed(t *testgroup.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "org", "repo")
+	t.Require.NoError(os.MkdirAll(repo, 0o755))
+	runGit(t, repo, "init", "--quiet")
+	t.Require.NoError(os.Symlink("..", filepath.Join(root, "org", "loop")))
+
+	actual, err := git.FindReposInDir(root)
+	t.Require.NoError(err)
+	t.Equal([]string{repo}, actual)
+}
+
+func (*FindReposTests) Symlinks_outside_root_are_not_followed(t *testgroup.T) {
+	outside := t.TempDir()
+	runGit(t, outside, "init", "--quiet")
+
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	t.Require.NoError(os.MkdirAll(repo, 0o755))
+	runGit(t, repo, "init", "--quiet")
+	t.Require.NoError(os.Symlink(outside, filepath.Join(root, "elsewhere")))
+	t.Require.NoError(os.Symlink(filepath.Dir(outside), filepath.Join(root, "parent")))
+
+	actual, err := git.FindReposInDir(root)
+	t.Require.NoError(err)
+	t.Equal([]string{repo}, actual)
+}
+
+func (*FindReposTests) Missing_root(t *testgroup.T) {
+	_, err := git.FindReposInDir(filepath.Join(t.TempDir(), "missing"))
+	t.Error(err)
+}
+
+//------------------------------------------------------------------------------
+
+func runGit(t *testgroup.T, dir string, args ...string) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	t.Require.NoError(err, "git %v: %s", args, out)
+}
+
+// findReposWithRevParse is the original, serial implementation of FindReposInDir, which asks
+// git about every directory.
+func findReposWithRevParse(t *testgroup.T, root string) []string {
+	isRepoRoot, err := git.IsLocalRepoRoot(root)
+	t.Require.NoError(err)
+	if isRepoRoot {
+		return []string{root}
+	}
+
+	var repos []string
+
+	dirQueue := []string{root}
+
+	for len(dirQueue) > 0 {
+		dirPath := dirQueue[0]
+		dirQueue = dirQueue[1:]
+
+		dir, err := os.Open(dirPath)
+		t.Require.NoError(err)
+
+		dirInfos, err := dir.Readdir(0)
+		dir.Close()
+		t.Require.NoError(err, dirPath)
+
+		for _, fi := range dirInfos {
+			if !fi.IsDir() {
+				continue
+			}
+
+			path := filepath.Join(dirPath, fi.Name())
+			isRepoRoot, err := git.IsLocalRepoRoot(path)
+			t.Require.NoError(err)
+
+			if !isRepoRoot {
+				dirQueue = append(dirQueue, path)
+				continue
+			}
+
+			repos = append(repos, path)
+		}
+	}
+
+	sort.Strings(repos)
+	return repos
+}
@@ -6,14 +6,24 @@ package git
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
 var ErrNotRepoRoot = fmt.Errorf("not the repo root")
 
 func IsLocalRepoRoot(dir string) (bool, error) {
+	dir, err := filepath.Abs(dir) // --show-toplevel is always absolute
+	if err != nil {
+		return false, err
+	}
+
 	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
 	cmd.Dir = dir
 	out, err := cmd.CombinedOutput()
@@ -139,6 +149,22 @@ func (repo *LocalRepo) Remotes() (LocalRepoRemotes, error) {
 	// return *repo.allRemotes, nil
 }
 
+// SetRemoteURL changes the URL that the remote fetches from.
+func (repo *LocalRepo) SetRemoteURL(remote, url string) error {
+	cmd := exec.Command("git", "remote", "set-url", remote, url)
+	cmd.Dir = repo.Root
+	_, err := cmd.CombinedOutput()
+	return err
+}
+
+// AddRemote adds a remote without fetching from it.
+func (repo *LocalRepo) AddRemote(remote, url string) error {
+	cmd := exec.Command("git", "remote", "add", remote, url)
+	cmd.Dir = repo.Root
+	_, err := cmd.CombinedOutput()
+	return err
+}
+
 type LocalRepoBranches map[string]LocalRepoBranch // branch name -> details
 
 type LocalRepoBranch struct {
@@ -178,6 +204,18 @@ func (repo *LocalRepo) LocalBranches() (branches LocalRepoBranches, current stri
 	return branches, current, nil
 }
 
+func (repo *LocalRepo) CreateBranch(branch, startPoint string) error {
+	cmd := exec.Command("git", "branch", "--no-track", branch)
+	if startPoint != "" {
+		cmd.Args = append(cmd.Args, startPoint)
+	}
+	// fmt.Printf("DEBUG: %v\n", cmd.Args)
+
+	cmd.Dir = repo.Root
+	_, err := cmd.CombinedOutput()
+	return err
+}
+
 func (repo *LocalRepo) DeleteBranch(branch string, force bool) error {
 	cmd := exec.Command("git", "branch", "--delete")
 	if force {
@@ -220,6 +258,139 @@ func (repo *LocalRepo) FetchAllAndPrune() (updated bool, err error) {
 	return false, err
 }
 
+// HasUnpushedCommits reports whether any commits on the branch are missing from every remote.
+func (repo *LocalRepo) HasUnpushedCommits(branch string) (bool, error) {
+	cmd := exec.Command("git", "rev-list", "--count", branch, "--not", "--remotes")
+	// fmt.Printf("DEBUG: %v\n", cmd.Args)
+	cmd.Dir = repo.Root
+	out, err := cmd.Output()
+	if err != nil {
+		return false, err
+	}
+
+	return strings.TrimSpace(string(out)) != "0", nil
+}
+
+// UntrackedFilesIn returns the untracked files that treeish also has, which checking it out
+// would overwrite.
+func (repo *LocalRepo) UntrackedFilesIn(treeish string) ([]string, error) {
+	untracked, err := repo.listFiles("ls-files", "-z", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	if len(untracked) == 0 {
+		return nil, nil
+	}
+
+	inTree, err := repo.listFiles("ls-tree", "-z", "-r", "--name-only", treeish)
+	if err != nil {
+		return nil, err
+	}
+
+	var colliding []string
+	for file := range inTree {
+		if untracked[file] {
+			colliding = append(colliding, file)
+		}
+	}
+	sort.Strings(colliding)
+
+	return colliding, nil
+}
+
+// listFiles runs a git command that prints NUL-terminated paths and returns them as a set.
+func (repo *LocalRepo) listFiles(args ...string) (map[string]bool, error) {
+	cmd := exec.Command("git", args...)
+	// fmt.Printf("DEBUG: %v\n", cmd.Args)
+	cmd.Dir = repo.Root
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]bool{}
+	for _, file := range strings.Split(string(out), "\x00") {
+		if file != "" {
+			files[file] = true
+		}
+	}
+
+	return files, nil
+}
+
+// UnpushedTags returns the local tags that no remote has (pointing at the same object). When
+// no remote can be reached, every local tag is considered unpushed.
+func (repo *LocalRepo) UnpushedTags() ([]string, error) {
+	localTags, err := repo.listRefs("for-each-ref", "--format=%(objectname) %(refname)", "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+	if len(localTags) == 0 {
+		return nil, nil
+	}
+
+	remotes, err := repo.Remotes()
+	if err != nil {
+		return nil, err
+	}
+
+	pushed := map[string]bool{} // "objectname refname"
+	for name := range remotes {
+		remoteTags, err := repo.listRefs("ls-remote", "--tags", name)
+		if err != nil {
+			continue // unreachable, so it can't vouch for any tags
+		}
+		for tag := range remoteTags {
+			pushed[tag] = true
+		}
+	}
+
+	var unpushed []string
+	for tag := range localTags {
+		if !pushed[tag] {
+			unpushed = append(unpushed, strings.TrimPrefix(strings.Fields(tag)[1], "refs/tags/"))
+		}
+	}
+	sort.Strings(unpushed)
+
+	return unpushed, nil
+}
+
+// listRefs runs a git command that prints "<objectname> <refname>" lines and returns them as a
+// set, with the whitespace between the fields normalized to a single space.
+func (repo *LocalRepo) listRefs(args ...string) (map[string]bool, error) {
+	cmd := exec.Command("git", args...)
+	// fmt.Printf("DEBUG: %v\n", cmd.Args)
+	cmd.Dir = repo.Root
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	refs := map[string]bool{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		refs[fields[0]+" "+fields[1]] = true
+	}
+
+	return refs, scanner.Err()
+}
+
+// KeepReset resets the current branch, index, and working tree to startPoint, but fails instead
+// of overwriting local changes or untracked files.
+func (repo *LocalRepo) KeepReset(startPoint string) error {
+	cmd := exec.Command("git", "reset", "--keep", "--quiet", startPoint)
+	// fmt.Printf("DEBUG: %v\n", cmd.Args)
+	cmd.Dir = repo.Root
+	_, err := cmd.CombinedOutput()
+	return err
+}
+
 func (repo *LocalRepo) ResetBranch(branch, startPoint string) error {
 	cmd := exec.Command("git", "branch", "--force", branch, startPoint)
 	// fmt.Printf("DEBUG: %v\n", cmd.Args)
@@ -243,3 +414,50 @@ func (repo *LocalRepo) SwitchToNewTrackingBranch(upstream string) error {
 	_, err := cmd.CombinedOutput()
 	return err
 }
+
+// RefExists takes anything rev-parse understands, like "origin/main" or "refs/stash".
+func (repo *LocalRepo) RefExists(ref string) (bool, error) {
+	return refExists(repo.Root, ref)
+}
+
+// RemoteDefaultBranch returns the branch that the remote's HEAD points to, like "main", or ""
+// if it isn't known locally.
+func (repo *LocalRepo) RemoteDefaultBranch(remote string) (string, error) {
+	cmd := exec.Command("git", "symbolic-ref", "--quiet", "--short", "refs/remotes/"+remote+"/HEAD")
+	cmd.Dir = repo.Root
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", nil // not a symbolic ref, or doesn't exist
+		}
+		return "", err
+	}
+
+	return strings.TrimPrefix(strings.TrimSpace(string(out)), remote+"/"), nil
+}
+
+// LastFetchTime returns the zero time if the repo has never been fetched.
+func (repo *LocalRepo) LastFetchTime() (time.Time, error) {
+	cmd := exec.Command("git", "rev-parse", "--git-path", "FETCH_HEAD")
+	cmd.Dir = repo.Root
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	fetchHead := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(fetchHead) {
+		fetchHead = filepath.Join(repo.Root, fetchHead)
+	}
+
+	info, err := os.Stat(fetchHead)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+
+	return info.ModTime(), nil
+}
@@ -6,21 +6,29 @@ package git
 import (
 	// "bufio"
 	"bytes"
-	// "fmt"
+	"errors"
+	"fmt"
 	"os/exec"
+	"strconv"
 	"strings"
 )
 
 type Status struct {
-	BranchHead string
+	BranchOID      string // "(initial)" before the first commit
+	BranchHead     string // "(detached)" when HEAD is detached
+	BranchUpstream string // empty when there's no upstream
+	Ahead          int    // commits ahead of the upstream
+	Behind         int    // commits behind the upstream
+	UpstreamGone   bool   // the upstream is configured but doesn't exist anymore
 
 	ChangedOrRenamed bool
 	Unmerged         bool
 	Untracked        bool
 	Ignored          bool
+	Stashed          bool
 }
 
-func getStatus(path string) (Status, error) {
+func GetStatus(path string) (Status, error) {
 	var status Status
 
 	cmd := exec.Command("git", "status", "--null", "--porcelain=v2",
@@ -32,20 +40,26 @@ func getStatus(path string) (Status, error) {
 		return status, err
 	}
 
-	for _, line := range bytes.Split(output, []byte{0}) {
+	hasAB := false // branch.ab is left out when the upstream is gone
+
+	fields := bytes.Split(output, []byte{0})
+	for i := 0; i < len(fields); i++ {
+		line := fields[i]
 		if len(line) == 0 {
 			continue
 		}
 		switch line[0] {
 		case '#':
-			parts := strings.Split(string(line), " ")
-			if parts[1] == "branch.head" {
-				status.BranchHead = parts[2]
+			if err := status.parseHeader(string(line)); err != nil {
+				return status, err
 			}
+			hasAB = hasAB || bytes.HasPrefix(line, []byte("# branch.ab "))
 
-		case '1', // changed
-			'2': // renamed or copied
+		case '1': // changed
+			status.ChangedOrRenamed = true
+		case '2': // renamed or copied
 			status.ChangedOrRenamed = true
+			i++ // skip the original path, which is in its own field
 		case 'u': // unmerged
 			status.Unmerged = true
 		case '?': // untracked
@@ -56,10 +70,68 @@ func getStatus(path string) (Status, error) {
 
 	}
 
+	if status.BranchUpstream != "" && !hasAB {
+		status.UpstreamGone = true
+	}
+
+	status.Stashed, err = hasStash(path)
+	if err != nil {
+		return status, err
+	}
+
 	return status, nil
 }
 
-// git rev-parse refs/stash
+func (status *Status) parseHeader(line string) error {
+	parts := strings.Split(line, " ")
+	if len(parts) < 3 {
+		return nil
+	}
+
+	switch parts[1] {
+	case "branch.oid":
+		status.BranchOID = parts[2]
+	case "branch.head":
+		status.BranchHead = parts[2]
+	case "branch.upstream":
+		status.BranchUpstream = parts[2]
+	case "branch.ab": // # branch.ab +<ahead> -<behind>
+		if len(parts) != 4 {
+			return fmt.Errorf("unexpected status header %q", line)
+		}
+		ahead, err := strconv.Atoi(strings.TrimPrefix(parts[2], "+"))
+		if err != nil {
+			return fmt.Errorf("unexpected status header %q: %w", line, err)
+		}
+		behind, err := strconv.Atoi(strings.TrimPrefix(parts[3], "-"))
+		if err != nil {
+			return fmt.Errorf("unexpected status header %q: %w", line, err)
+		}
+		status.Ahead, status.Behind = ahead, behind
+	}
+
+	return nil
+}
+
+func hasStash(path string) (bool, error) {
+	return refExists(path, "refs/stash")
+}
+
+func refExists(path, ref string) (bool, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", ref)
+	cmd.Dir = path
+
+	_, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return false, nil // no such ref
+		}
+		return false, err
+	}
+
+	return true, nil
+}
 
 // git status --porcelain
 
@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package git_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bloomberg/go-testgroup"
+	"github.com/mikesep/frond/internal/git"
+)
+
+func Test_GetStatus(t *testing.T) {
+	testgroup.RunInParallel(t, &StatusTests{})
+}
+
+type StatusTests struct{}
+
+func (*StatusTests) Ahead_and_behind_upstream(t *testgroup.T) {
+	root := t.TempDir()
+	upstream := filepath.Join(root, "upstream")
+	clone := filepath.Join(root, "clone")
+
+	t.Require.NoError(os.MkdirAll(upstream, 0o755))
+	runGit(t, upstream, "init", "--quiet", "--initial-branch=main")
+	commitEmpty(t, upstream, "one")
+	runGit(t, root, "clone", "--quiet", upstream, clone)
+
+	commitEmpty(t, upstream, "two")
+	commitEmpty(t, upstream, "three")
+	runGit(t, clone, "fetch", "--quiet")
+	commitEmpty(t, clone, "four")
+
+	st, err := git.GetStatus(clone)
+	t.Require.NoError(err)
+
+	t.Equal("main", st.BranchHead)
+	t.Equal("origin/main", st.BranchUpstream)
+	t.Equal(1, st.Ahead)
+	t.Equal(2, st.Behind)
+	t.False(st.UpstreamGone)
+	t.False(st.Stashed)
+}
+
+func (*StatusTests) Flags_and_stash(t *testgroup.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init", "--quiet", "--initial-branch=main")
+
+	t.Require.NoError(os.WriteFile(filepath.Join(repo, "tracked"), []byte("1\n"), 0o644))
+	runGit(t, repo, "add", "tracked")
+	commitEmpty(t, repo, "add tracked")
+
+	t.Require.NoError(os.WriteFile(filepath.Join(repo, "tracked"), []byte("2\n"), 0o644))
+	runGit(t, repo, "stash", "--quiet")
+
+	t.Require.NoError(os.WriteFile(filepath.Join(repo, "tracked"), []byte("3\n"), 0o644))
+	t.Require.NoError(os.WriteFile(filepath.Join(repo, "untracked"), []byte("4\n"), 0o644))
+
+	st, err := git.GetStatus(repo)
+	t.Require.NoError(err)
+
+	t.Equal("main", st.BranchHead)
+	t.Empty(st.BranchUpstream)
+	t.True(st.ChangedOrRenamed)
+	t.True(st.Untracked)
+	t.True(st.Stashed)
+	t.False(st.Unmerged)
+}
+
+func commitEmpty(t *testgroup.T, dir, message string) {
+	runGit(t, dir, "-c", "user.name=frond", "-c", "user.email=frond@example.com",
+		"commit", "--quiet", "--allow-empty", "--message="+message)
+}
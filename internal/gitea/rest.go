@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const pageLimit = 50 // Gitea's default maximum, though servers can lower it
+
+var ErrNotFound = errors.New("not found")
+
+type Repo struct {
+	Name     string `json:"name"`
+	FullName string `json:"full_name"`
+	Owner    Owner  `json:"owner"`
+	CloneURL string `json:"clone_url"`
+	SSHURL   string `json:"ssh_url"`
+
+	Archived      bool     `json:"archived"`
+	DefaultBranch string   `json:"default_branch"`
+	Fork          bool     `json:"fork"`
+	Internal      bool     `json:"internal"`
+	Language      string   `json:"language"`
+	Private       bool     `json:"private"`
+	Template      bool     `json:"template"`
+	Topics        []string `json:"topics"`
+
+	Size       int       `json:"size"` // in KB
+	StarsCount int       `json:"stars_count"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	Parent *Repo `json:"parent"` // only set for forks
+}
+
+type Owner struct {
+	Login string `json:"login"`
+}
+
+type AccountType string
+
+const (
+	Organization AccountType = "Organization"
+	User         AccountType = "User"
+)
+
+type Account struct {
+	Login string
+	Type  AccountType
+}
+
+// GetAccount looks for an org with the name first, then for a user.
+func (sat ServerAndToken) GetAccount(ctx context.Context, name string) (Account, error) {
+	var acct struct {
+		UserName string `json:"username"` // orgs
+		Login    string `json:"login"`    // users
+	}
+
+	err := sat.getJSON(ctx, fmt.Sprintf("%s/orgs/%s", sat.restV1URL(), url.PathEscape(name)), &acct)
+	if err == nil {
+		return Account{Login: acct.UserName, Type: Organization}, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return Account{}, err
+	}
+
+	err = sat.getJSON(ctx, fmt.Sprintf("%s/users/%s", sat.restV1URL(), url.PathEscape(name)), &acct)
+	if errors.Is(err, ErrNotFound) {
+		return Account{}, fmt.Errorf("failed to find account %q", name)
+	}
+	if err != nil {
+		return Account{}, err
+	}
+
+	return Account{Login: acct.Login, Type: User}, nil
+}
+
+func (sat ServerAndToken) GetRepo(ctx context.Context, fullName string) (Repo, error) {
+	owner, name, ok := cut(fullName, "/")
+	if !ok {
+		return Repo{}, fmt.Errorf("expected owner/repo, got %q", fullName)
+	}
+
+	var repo Repo
+	apiURL := fmt.Sprintf("%s/repos/%s/%s", sat.restV1URL(), url.PathEscape(owner), url.PathEscape(name))
+	err := sat.getJSON(ctx, apiURL, &repo)
+	return repo, err
+}
+
+func (sat ServerAndToken) ListRepos(ctx context.Context, progress io.Writer, account Account,
+) ([]Repo, error) {
+	var results []Repo
+
+	orgsOrUsers := "orgs"
+	if account.Type == User {
+		orgsOrUsers = "users"
+	}
+
+	baseURL := fmt.Sprintf("%s/%s/%s/repos?limit=%d",
+		sat.restV1URL(), orgsOrUsers, url.PathEscape(account.Login), pageLimit)
+
+	for page := 1; ; page++ {
+		fmt.Fprint(progress, ".") // print a dot for each iteration
+
+		var repos []Repo
+		if err := sat.getJSON(ctx, fmt.Sprintf("%s&page=%d", baseURL, page), &repos); err != nil {
+			return results, err
+		}
+
+		// Servers can be configured to return fewer repos per page than asked for, so only an
+		// empty page means there are no more.
+		if len(repos) == 0 {
+			return results, nil
+		}
+
+		results = append(results, repos...)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (sat ServerAndToken) getJSON(ctx context.Context, apiURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "token "+sat.Token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := sat.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// yay!
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrNotFound, apiURL)
+	default:
+		return fmt.Errorf("bad status code %d from %s", resp.StatusCode, apiURL)
+	}
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(respBytes, v)
+}
+
+// cut is strings.Cut, which needs Go 1.18.
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
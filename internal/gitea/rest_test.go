@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package gitea_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mikesep/frond/internal/gitea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFakeServer(t *testing.T) gitea.ServerAndToken {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/v1/orgs/tools", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 1, "username": "tools"}`)
+	})
+	mux.HandleFunc("/api/v1/users/alice", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 2, "login": "alice"}`)
+	})
+
+	// 60 repos, with at most 30 per page (like MAX_RESPONSE_ITEMS = 30), so there are two full
+	// pages of them and then an empty one
+	mux.HandleFunc("/api/v1/orgs/tools/repos", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token secret", r.Header.Get("Authorization"))
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if limit > 30 {
+			limit = 30
+		}
+
+		var repos []gitea.Repo
+		for i := (page - 1) * limit; i < page*limit && i < 60; i++ {
+			repos = append(repos, gitea.Repo{
+				Name:     fmt.Sprintf("repo%02d", i),
+				FullName: fmt.Sprintf("tools/repo%02d", i),
+				Owner:    gitea.Owner{Login: "tools"},
+			})
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(repos))
+	})
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	ts := httptest.NewTLSServer(mux)
+	t.Cleanup(ts.Close)
+
+	return gitea.ServerAndToken{
+		Server: strings.TrimPrefix(ts.URL, "https://"),
+		Token:  "secret",
+		Client: ts.Client(),
+	}
+}
+
+func Test_GetAccount(t *testing.T) {
+	sat := newFakeServer(t)
+	ctx := context.Background()
+
+	acct, err := sat.GetAccount(ctx, "tools")
+	require.NoError(t, err)
+	assert.Equal(t, gitea.Account{Login: "tools", Type: gitea.Organization}, acct)
+
+	acct, err = sat.GetAccount(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, gitea.Account{Login: "alice", Type: gitea.User}, acct)
+
+	_, err = sat.GetAccount(ctx, "nobody")
+	assert.EqualError(t, err, `failed to find account "nobody"`)
+}
+
+func Test_ListRepos(t *testing.T) {
+	sat := newFakeServer(t)
+
+	var progress bytes.Buffer
+	repos, err := sat.ListRepos(context.Background(), &progress,
+		gitea.Account{Login: "tools", Type: gitea.Organization})
+	require.NoError(t, err)
+
+	assert.Equal(t, "...", progress.String())
+	require.Len(t, repos, 60)
+	assert.Equal(t, "tools/repo00", repos[0].FullName)
+	assert.Equal(t, "tools/repo59", repos[59].FullName)
+}
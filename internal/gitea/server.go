@@ -0,0 +1,43 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package gitea
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/mikesep/frond/internal/probe"
+)
+
+// ServerAndToken works with Gitea and with Forgejo, which shares its API.
+type ServerAndToken struct {
+	Server string // gitea.example.com
+	Token  string
+
+	Client *http.Client // nil means http.DefaultClient
+}
+
+func (sat *ServerAndToken) restV1URL() string {
+	return "https://" + sat.Server + "/api/v1"
+}
+
+func (sat *ServerAndToken) httpClient() *http.Client {
+	if sat.Client == nil {
+		return http.DefaultClient
+	}
+	return sat.Client
+}
+
+// DetectServer checks for the version endpoint, which doesn't need authentication. A nil client
+// means http.DefaultClient.
+func DetectServer(ctx context.Context, client *http.Client, server string) bool {
+	sat := ServerAndToken{Server: server}
+	return probe.Check(ctx, client, http.MethodGet, sat.restV1URL()+"/version",
+		func(resp *http.Response) bool {
+			var version struct {
+				Version string `json:"version"`
+			}
+			return probe.DecodeJSON(resp, &version) && version.Version != ""
+		})
+}
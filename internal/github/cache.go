@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotCached is returned in offline mode for requests that haven't been cached.
+var ErrNotCached = errors.New("not cached")
+
+// Cache keeps GET responses on disk, so that later requests for them can be conditional. GitHub
+// doesn't count 304 Not Modified responses against the rate limit.
+type Cache struct {
+	Dir string
+
+	// Identity says whose listings these are, since different credentials can see different
+	// repos. It's stable across token refreshes, unlike the token itself.
+	Identity string
+
+	Refresh bool // don't send If-None-Match, but still update the cache
+	Offline bool // don't send any requests; only use the cache
+}
+
+type cacheEntry struct {
+	ETag string          `json:"etag"`
+	Link string          `json:"link,omitempty"` // for paging
+	Body json.RawMessage `json:"body"`
+}
+
+func (e cacheEntry) header() http.Header {
+	h := http.Header{}
+	h.Set("ETag", e.ETag)
+	if e.Link != "" {
+		h.Set("Link", e.Link)
+	}
+	return h
+}
+
+func (c *Cache) path(apiURL string) string {
+	sum := sha256.Sum256([]byte(c.Identity + "\x00" + apiURL))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *Cache) load(apiURL string) (cacheEntry, bool) {
+	var entry cacheEntry
+
+	data, err := ioutil.ReadFile(c.path(apiURL))
+	if err != nil {
+		return entry, false
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return entry, false // treat a corrupt entry like a missing one
+	}
+
+	return entry, true
+}
+
+func (c *Cache) store(apiURL string, entry cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(c.Dir, 0o700); err != nil {
+		return err
+	}
+
+	// Write and rename, so that an interrupted write doesn't leave a partial entry behind.
+	path := c.path(apiURL)
+	tmp, err := ioutil.TempFile(c.Dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to cache %s: %w", apiURL, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package github_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mikesep/frond/internal/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type cacheTestServer struct {
+	github.ServerAndToken
+
+	full, notModified int // response counts
+}
+
+// newCacheTestServer serves two pages of repos, with an ETag for each page.
+func newCacheTestServer(t *testing.T) *cacheTestServer {
+	s := &cacheTestServer{}
+
+	var ts *httptest.Server
+	ts = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		etag := fmt.Sprintf(`"page-%s"`, page)
+
+		if r.Header.Get("If-None-Match") == etag {
+			s.notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		s.full++
+
+		w.Header().Set("ETag", etag)
+		if page == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s&page=2>; rel="next"`, ts.URL, r.URL.String()))
+			fmt.Fprint(w, `[{"full_name": "bloomberg/one"}]`)
+			return
+		}
+		fmt.Fprint(w, `[{"full_name": "bloomberg/two"}]`)
+	}))
+	t.Cleanup(ts.Close)
+
+	s.ServerAndToken = github.ServerAndToken{
+		Server: strings.TrimPrefix(ts.URL, "https://"),
+		Token:  "secret",
+		Client: ts.Client(),
+		Cache:  &github.Cache{Dir: t.TempDir(), Identity: "alice"},
+	}
+	return s
+}
+
+func (s *cacheTestServer) listRepoNames() ([]string, error) {
+	repos, err := s.ListRepos(context.Background(), ioutil.Discard,
+		github.Account{Login: "bloomberg", Type: "Organization"}, github.AllRepos)
+
+	var names []string
+	for _, r := range repos {
+		names = append(names, r.FullName)
+	}
+	return names, err
+}
+
+func Test_Cache(t *testing.T) {
+	s := newCacheTestServer(t)
+	want := []string{"bloomberg/one", "bloomberg/two"}
+
+	names, err := s.listRepoNames()
+	require.NoError(t, err)
+	assert.Equal(t, want, names)
+	assert.Equal(t, 2, s.full)
+	assert.Equal(t, 0, s.notModified)
+
+	t.Run("unchanged pages come from the cache", func(t *testing.T) {
+		names, err := s.listRepoNames()
+		require.NoError(t, err)
+		assert.Equal(t, want, names)
+		assert.Equal(t, 2, s.full)
+		assert.Equal(t, 2, s.notModified)
+	})
+
+	t.Run("refresh downloads everything", func(t *testing.T) {
+		s.Cache.Refresh = true
+		defer func() { s.Cache.Refresh = false }()
+
+		names, err := s.listRepoNames()
+		require.NoError(t, err)
+		assert.Equal(t, want, names)
+		assert.Equal(t, 4, s.full)
+		assert.Equal(t, 2, s.notModified)
+	})
+
+	t.Run("offline doesn't send requests", func(t *testing.T) {
+		s.Cache.Offline = true
+		defer func() { s.Cache.Offline = false }()
+
+		names, err := s.listRepoNames()
+		require.NoError(t, err)
+		assert.Equal(t, want, names)
+		assert.Equal(t, 4, s.full)
+		assert.Equal(t, 2, s.notModified)
+	})
+
+	t.Run("offline fails for uncached requests", func(t *testing.T) {
+		s.Cache.Offline = true
+		defer func() { s.Cache.Offline = false }()
+
+		s.Cache.Identity = "bob"
+		defer func() { s.Cache.Identity = "alice" }()
+
+		_, err := s.listRepoNames()
+		assert.True(t, errors.Is(err, github.ErrNotCached))
+	})
+
+	t.Run("a new token for the same identity still hits the cache", func(t *testing.T) {
+		s.Token = "refreshed"
+		defer func() { s.Token = "secret" }()
+
+		names, err := s.listRepoNames()
+		require.NoError(t, err)
+		assert.Equal(t, want, names)
+		assert.Equal(t, 4, s.full)
+		assert.Equal(t, 4, s.notModified)
+	})
+}
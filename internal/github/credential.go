@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/mikesep/frond/internal/git"
+)
+
+// GitCredential is where a token from git's credential helpers came from. Whether the API
+// accepted the token is reported back to the helpers, so that they can store a good token and
+// forget a bad one instead of handing it out again. Each is only reported once, but a token
+// that worked can still be rejected later, like when it's revoked in the middle of a sync.
+type GitCredential struct {
+	git.Credential
+
+	mu       sync.Mutex
+	approved bool
+	rejected bool
+
+	// for tests; nil means git.ApproveCredential and git.RejectCredential
+	approve func(git.Credential) error
+	reject  func(git.Credential) error
+}
+
+func (gc *GitCredential) report(worked bool) error {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	if worked {
+		if gc.approved || gc.rejected {
+			return nil
+		}
+		gc.approved = true
+
+		if gc.approve != nil {
+			return gc.approve(gc.Credential)
+		}
+		return git.ApproveCredential(gc.Credential)
+	}
+
+	if gc.rejected {
+		return nil
+	}
+	gc.rejected = true
+
+	if gc.reject != nil {
+		return gc.reject(gc.Credential)
+	}
+	return git.RejectCredential(gc.Credential)
+}
+
+// reportCredential is a no-op unless the token came from git's credential helpers. Failing to
+// report isn't fatal, so it's only mentioned on the console.
+func (sat ServerAndToken) reportCredential(worked bool) {
+	if sat.GitCredential == nil {
+		return
+	}
+
+	if err := sat.GitCredential.report(worked); err != nil && sat.Console != nil {
+		fmt.Fprintf(sat.Console, " (warning: %v)", err)
+	}
+}
@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/mikesep/frond/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingCredential records what it's asked to report instead of running git.
+func recordingCredential(reports *[]string) *GitCredential {
+	return &GitCredential{
+		Credential: git.Credential{Protocol: "https", Host: "github.com", Password: "t0ken"},
+		approve: func(cred git.Credential) error {
+			*reports = append(*reports, "approve "+cred.Password)
+			return nil
+		},
+		reject: func(cred git.Credential) error {
+			*reports = append(*reports, "reject "+cred.Password)
+			return nil
+		},
+	}
+}
+
+func Test_ApprovesWorkingCredentialOnce(t *testing.T) {
+	sat, _ := newFlakyServer(t, 0, nil)
+
+	var reports []string
+	sat.GitCredential = recordingCredential(&reports)
+
+	for i := 0; i < 2; i++ {
+		_, err := sat.GetAccount(context.Background(), "bloomberg")
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{"approve t0ken"}, reports)
+}
+
+func Test_RejectsUnauthorizedCredential(t *testing.T) {
+	sat, _ := newFlakyServer(t, 10, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"message": "Bad credentials"}`)
+	})
+
+	var reports []string
+	sat.GitCredential = recordingCredential(&reports)
+
+	_, err := sat.GetAccount(context.Background(), "bloomberg")
+	assert.Error(t, err)
+	assert.Equal(t, []string{"reject t0ken"}, reports)
+}
+
+func Test_RejectsCredentialThatStopsWorking(t *testing.T) {
+	var reports []string
+	gc := recordingCredential(&reports)
+
+	for _, worked := range []bool{true, true, false, false, true} {
+		require.NoError(t, gc.report(worked))
+	}
+
+	assert.Equal(t, []string{"approve t0ken", "reject t0ken"}, reports)
+}
+
+func Test_OtherFailuresDoNotReportCredential(t *testing.T) {
+	sat, _ := newFlakyServer(t, 10, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	var reports []string
+	sat.GitCredential = recordingCredential(&reports)
+
+	_, err := sat.GetAccount(context.Background(), "nobody")
+	assert.Error(t, err)
+	assert.Empty(t, reports)
+}
+
+func Test_ReportFailureIsOnlyAWarning(t *testing.T) {
+	sat, _ := newFlakyServer(t, 0, nil)
+
+	var console bytes.Buffer
+	sat.Console = &console
+	sat.GitCredential = &GitCredential{
+		approve: func(git.Credential) error { return errors.New("helper is broken") },
+	}
+
+	_, err := sat.GetAccount(context.Background(), "bloomberg")
+	require.NoError(t, err)
+	assert.Equal(t, " (warning: helper is broken)", console.String())
+}
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrRateLimited matches (with errors.Is) a StatusError caused by a primary or secondary rate
+// limit.
+var ErrRateLimited = errors.New("rate limited")
+
+// StatusError is returned for responses that aren't successful, after any retries.
+type StatusError struct {
+	StatusCode int
+	URL        string
+	Message    string // from the response body, when there is one
+
+	RateLimit  RateLimit     // zero when the response didn't include it
+	RetryAfter time.Duration // zero when the response didn't include it
+
+	GraphQLType string // the type of a GraphQL error in an otherwise successful response
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("bad status code %d from %s", e.StatusCode, e.URL)
+	if e.GraphQLType != "" {
+		msg = fmt.Sprintf("graphql error %s from %s", e.GraphQLType, e.URL)
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+func (e *StatusError) Is(target error) bool {
+	return target == ErrRateLimited && e.RateLimited()
+}
+
+// RateLimited tells 403s caused by rate limits apart from ones caused by permissions. GraphQL
+// reports its rate limits in 200 responses instead.
+func (e *StatusError) RateLimited() bool {
+	if e.GraphQLType == "RATE_LIMITED" {
+		return true
+	}
+
+	switch e.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		return e.RetryAfter > 0 || e.RateLimit.Exhausted() ||
+			strings.Contains(strings.ToLower(e.Message), "rate limit")
+	}
+	return false
+}
+
+// Retryable is true for rate limits and for server-side failures.
+func (e *StatusError) Retryable() bool {
+	return e.RateLimited() || e.StatusCode >= 500
+}
+
+// newStatusError consumes and closes the response body.
+func newStatusError(resp *http.Response, apiURL string) *StatusError {
+	defer resp.Body.Close()
+
+	e := &StatusError{
+		StatusCode: resp.StatusCode,
+		URL:        apiURL,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+	e.RateLimit, _ = parseRateLimit(resp.Header)
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return e
+	}
+
+	var jsonBody struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &jsonBody) == nil {
+		e.Message = jsonBody.Message
+	} else {
+		e.Message = strings.TrimSpace(string(body))
+	}
+
+	return e
+}
@@ -0,0 +1,280 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func (sat *ServerAndToken) graphQLURL() string {
+	return sat.apiURL() + "/graphql"
+}
+
+// listReposQuery gets everything filterRepo looks at, 100 repos at a time. Its affiliations
+// match REST's type=all, which includes the repos a user collaborates on or can see through an
+// org.
+const listReposQuery = `
+query($login: String!, $cursor: String) {
+  repositoryOwner(login: $login) {
+    repositories(first: 100, after: $cursor,
+                 ownerAffiliations: [OWNER, COLLABORATOR, ORGANIZATION_MEMBER],
+                 orderBy: {field: NAME, direction: ASC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        name
+        nameWithOwner
+        url
+        sshUrl
+        owner { login __typename }
+        defaultBranchRef { name }
+        primaryLanguage { name }
+        repositoryTopics(first: 100) { nodes { topic { name } } }
+        isArchived
+        isFork
+        isTemplate
+        visibility
+        pushedAt
+        diskUsage
+        stargazerCount
+        parent { nameWithOwner url sshUrl }
+      }
+    }
+  }
+}`
+
+type graphQLRepo struct {
+	Name          string `json:"name"`
+	NameWithOwner string `json:"nameWithOwner"`
+	URL           string `json:"url"`
+	SSHURL        string `json:"sshUrl"`
+	Owner         struct {
+		Login    string `json:"login"`
+		TypeName string `json:"__typename"`
+	} `json:"owner"`
+	DefaultBranchRef *struct {
+		Name string `json:"name"`
+	} `json:"defaultBranchRef"`
+	PrimaryLanguage *struct {
+		Name string `json:"name"`
+	} `json:"primaryLanguage"`
+	RepositoryTopics struct {
+		Nodes []struct {
+			Topic struct {
+				Name string `json:"name"`
+			} `json:"topic"`
+		} `json:"nodes"`
+	} `json:"repositoryTopics"`
+	IsArchived bool   `json:"isArchived"`
+	IsFork     bool   `json:"isFork"`
+	IsTemplate bool   `json:"isTemplate"`
+	Visibility string `json:"visibility"` // PUBLIC, PRIVATE, or INTERNAL
+
+	PushedAt       *time.Time `json:"pushedAt"`
+	DiskUsage      int        `json:"diskUsage"` // in KB
+	StargazerCount int        `json:"stargazerCount"`
+
+	Parent *struct {
+		NameWithOwner string `json:"nameWithOwner"`
+		URL           string `json:"url"`
+		SSHURL        string `json:"sshUrl"`
+	} `json:"parent"`
+}
+
+func (r graphQLRepo) toRepo() Repo {
+	repo := Repo{
+		Name:     r.Name,
+		FullName: r.NameWithOwner,
+		Account:  Account{Login: r.Owner.Login, Type: r.Owner.TypeName},
+		CloneURL: r.URL + ".git",
+		SSHURL:   r.SSHURL,
+
+		Archived:   r.IsArchived,
+		Fork:       r.IsFork,
+		IsTemplate: r.IsTemplate,
+		Private:    r.Visibility != "PUBLIC", // like REST, which says internal repos are private
+		Visibility: strings.ToLower(r.Visibility),
+
+		Size:            r.DiskUsage,
+		StargazersCount: r.StargazerCount,
+	}
+
+	if r.PushedAt != nil {
+		repo.PushedAt = *r.PushedAt
+	}
+
+	if r.Parent != nil {
+		repo.Parent = &Repo{
+			FullName: r.Parent.NameWithOwner,
+			CloneURL: r.Parent.URL + ".git",
+			SSHURL:   r.Parent.SSHURL,
+		}
+	}
+
+	if r.DefaultBranchRef != nil {
+		repo.DefaultBranch = r.DefaultBranchRef.Name
+	}
+	if r.PrimaryLanguage != nil {
+		repo.Language = r.PrimaryLanguage.Name
+	}
+	for _, n := range r.RepositoryTopics.Nodes {
+		repo.Topics = append(repo.Topics, n.Topic.Name)
+	}
+
+	return repo
+}
+
+// ListReposGraphQL is like ListRepos, but gets all of each repo's details in one paginated
+// query. Only AllRepos is supported.
+func (sat ServerAndToken) ListReposGraphQL(
+	ctx context.Context, progress io.Writer, account Account, repoType RepoType,
+) ([]Repo, error) {
+	if repoType != AllRepos {
+		return nil, fmt.Errorf("graphql: unsupported repo type %q", repoType)
+	}
+
+	var results []Repo
+
+	var cursor *string
+
+	for {
+		fmt.Fprint(progress, ".") // print a dot for each iteration
+
+		var data struct {
+			RepositoryOwner *struct {
+				Repositories struct {
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+					Nodes []graphQLRepo `json:"nodes"`
+				} `json:"repositories"`
+			} `json:"repositoryOwner"`
+		}
+
+		vars := map[string]interface{}{"login": account.Login, "cursor": cursor}
+		if err := sat.graphQL(ctx, listReposQuery, vars, &data); err != nil {
+			return results, err
+		}
+
+		if data.RepositoryOwner == nil {
+			return results, fmt.Errorf("failed to find account %q", account.Login)
+		}
+
+		repos := data.RepositoryOwner.Repositories
+		for _, r := range repos.Nodes {
+			results = append(results, r.toRepo())
+		}
+
+		if !repos.PageInfo.HasNextPage {
+			return results, nil
+		}
+		endCursor := repos.PageInfo.EndCursor
+		cursor = &endCursor
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (sat ServerAndToken) graphQL(
+	ctx context.Context, query string, vars map[string]interface{}, data interface{},
+) error {
+	if sat.Cache != nil && sat.Cache.Offline {
+		return fmt.Errorf("%w: GraphQL queries are never cached", ErrNotCached)
+	}
+
+	reqBytes, err := json.Marshal(map[string]interface{}{"query": query, "variables": vars})
+	if err != nil {
+		return err
+	}
+
+	apiURL := sat.graphQLURL()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(reqBytes))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "bearer "+sat.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	var gqlResp graphQLResponse
+	var readErr error
+	check := func(resp *http.Response) *StatusError {
+		gqlResp, readErr = readGraphQLResponse(resp)
+		if readErr == nil {
+			return gqlResp.rateLimitError(resp, apiURL)
+		}
+		return nil
+	}
+
+	// check reads and closes the body.
+	if _, err := sat.doChecked(req, check); err != nil {
+		return err
+	}
+	if readErr != nil {
+		return readErr
+	}
+
+	if len(gqlResp.Errors) > 0 {
+		messages := make([]string, 0, len(gqlResp.Errors))
+		for _, e := range gqlResp.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("graphql errors from %s: %s", apiURL, strings.Join(messages, "; "))
+	}
+
+	return json.Unmarshal(gqlResp.Data, data)
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// readGraphQLResponse closes the body.
+func readGraphQLResponse(resp *http.Response) (graphQLResponse, error) {
+	defer resp.Body.Close()
+
+	var gqlResp graphQLResponse
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return gqlResp, err
+	}
+
+	return gqlResp, json.Unmarshal(respBytes, &gqlResp)
+}
+
+// rateLimitError returns nil unless GraphQL said the query was rate limited, which it does
+// with a 200 response.
+func (r graphQLResponse) rateLimitError(resp *http.Response, apiURL string) *StatusError {
+	for _, e := range r.Errors {
+		if e.Type != "RATE_LIMITED" {
+			continue
+		}
+
+		statusErr := &StatusError{
+			StatusCode:  resp.StatusCode,
+			URL:         apiURL,
+			Message:     e.Message,
+			RetryAfter:  parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+			GraphQLType: e.Type,
+		}
+		statusErr.RateLimit, _ = parseRateLimit(resp.Header)
+		return statusErr
+	}
+
+	return nil
+}
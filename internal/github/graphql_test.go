@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package github_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikesep/frond/internal/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFakeGraphQLServer(t *testing.T) github.ServerAndToken {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/graphql", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "bearer secret", r.Header.Get("Authorization"))
+
+		var req struct {
+			Query     string `json:"query"`
+			Variables struct {
+				Login  string  `json:"login"`
+				Cursor *string `json:"cursor"`
+			} `json:"variables"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		// the same repos as REST's type=all
+		assert.Contains(t, req.Query, "ownerAffiliations: [OWNER, COLLABORATOR, ORGANIZATION_MEMBER]")
+
+		switch {
+		case req.Variables.Login != "bloomberg":
+			fmt.Fprint(w, `{"data": {"repositoryOwner": null}}`)
+		case req.Variables.Cursor == nil:
+			fmt.Fprint(w, `{"data": {"repositoryOwner": {"repositories": {
+				"pageInfo": {"hasNextPage": true, "endCursor": "abc"},
+				"nodes": [{
+					"name": "go-testgroup",
+					"nameWithOwner": "bloomberg/go-testgroup",
+					"url": "https://ghes.example.com/bloomberg/go-testgroup",
+					"owner": {"login": "bloomberg", "__typename": "Organization"},
+					"defaultBranchRef": {"name": "main"},
+					"primaryLanguage": {"name": "Go"},
+					"repositoryTopics": {"nodes": [{"topic": {"name": "testing"}}]},
+					"isArchived": false, "isFork": false, "isTemplate": false,
+					"visibility": "PUBLIC",
+					"pushedAt": "2021-06-01T12:00:00Z", "diskUsage": 2048, "stargazerCount": 42
+				}]}}}}`)
+		case *req.Variables.Cursor == "abc":
+			fmt.Fprint(w, `{"data": {"repositoryOwner": {"repositories": {
+				"pageInfo": {"hasNextPage": false, "endCursor": "def"},
+				"nodes": [{
+					"name": "empty",
+					"nameWithOwner": "bloomberg/empty",
+					"url": "https://ghes.example.com/bloomberg/empty",
+					"owner": {"login": "bloomberg", "__typename": "Organization"},
+					"defaultBranchRef": null,
+					"primaryLanguage": null,
+					"repositoryTopics": {"nodes": []},
+					"isArchived": true, "isFork": true, "isTemplate": true,
+					"visibility": "INTERNAL",
+					"parent": {"nameWithOwner": "upstream/empty",
+						"url": "https://ghes.example.com/upstream/empty",
+						"sshUrl": "git@ghes.example.com:upstream/empty.git"}
+				}]}}}}`)
+		default:
+			fmt.Fprint(w, `{"errors": [{"message": "bad cursor"}]}`)
+		}
+	})
+
+	ts := httptest.NewTLSServer(mux)
+	t.Cleanup(ts.Close)
+
+	return github.ServerAndToken{
+		Server: strings.TrimPrefix(ts.URL, "https://"),
+		Token:  "secret",
+		Client: ts.Client(),
+	}
+}
+
+func Test_ListReposGraphQL(t *testing.T) {
+	sat := newFakeGraphQLServer(t)
+	ctx := context.Background()
+
+	var progress bytes.Buffer
+	repos, err := sat.ListReposGraphQL(ctx, &progress,
+		github.Account{Login: "bloomberg", Type: "Organization"}, github.AllRepos)
+	require.NoError(t, err)
+
+	assert.Equal(t, "..", progress.String())
+	assert.Equal(t, []github.Repo{
+		{
+			Name:          "go-testgroup",
+			FullName:      "bloomberg/go-testgroup",
+			Account:       github.Account{Login: "bloomberg", Type: "Organization"},
+			CloneURL:      "https://ghes.example.com/bloomberg/go-testgroup.git",
+			DefaultBranch: "main",
+			Language:      "Go",
+			Topics:        []string{"testing"},
+			Visibility:    "public",
+
+			PushedAt:        time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
+			Size:            2048,
+			StargazersCount: 42,
+		},
+		{
+			Name:       "empty",
+			FullName:   "bloomberg/empty",
+			Account:    github.Account{Login: "bloomberg", Type: "Organization"},
+			CloneURL:   "https://ghes.example.com/bloomberg/empty.git",
+			Archived:   true,
+			Fork:       true,
+			IsTemplate: true,
+			Private:    true,
+			Visibility: "internal",
+			Parent: &github.Repo{
+				FullName: "upstream/empty",
+				CloneURL: "https://ghes.example.com/upstream/empty.git",
+				SSHURL:   "git@ghes.example.com:upstream/empty.git",
+			},
+		},
+	}, repos)
+
+	_, err = sat.ListReposGraphQL(ctx, &progress,
+		github.Account{Login: "nobody", Type: "User"}, github.AllRepos)
+	assert.EqualError(t, err, `failed to find account "nobody"`)
+
+	_, err = sat.ListReposGraphQL(ctx, &progress,
+		github.Account{Login: "bloomberg", Type: "Organization"}, github.PublicRepos)
+	assert.Error(t, err)
+}
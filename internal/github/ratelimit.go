@@ -0,0 +1,178 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimit is what the X-RateLimit-* headers said about the API quota.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+func (rl RateLimit) Exhausted() bool {
+	return rl.Limit > 0 && rl.Remaining == 0
+}
+
+func (rl RateLimit) String() string {
+	return fmt.Sprintf("%d of %d API requests left until %s",
+		rl.Remaining, rl.Limit, rl.Reset.Local().Format("15:04"))
+}
+
+func parseRateLimit(h http.Header) (RateLimit, bool) {
+	limit, err := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+	reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return RateLimit{}, false
+	}
+
+	return RateLimit{Limit: limit, Remaining: remaining, Reset: time.Unix(reset, 0)}, true
+}
+
+// parseRetryAfter handles both forms of the header: seconds and an HTTP date.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if when, err := http.ParseTime(header); err == nil && when.After(now) {
+		return when.Sub(now)
+	}
+	return 0
+}
+
+//------------------------------------------------------------------------------
+
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration // doubled after each attempt...
+	maxDelay    time.Duration // ...up to this
+
+	// Waiting for a primary rate limit to reset can take up to an hour, so give up instead when
+	// the wait would be longer than this.
+	maxRateLimitWait time.Duration
+}
+
+var defaultRetryPolicy = retryPolicy{
+	maxAttempts:      5,
+	baseDelay:        time.Second,
+	maxDelay:         30 * time.Second,
+	maxRateLimitWait: 5 * time.Minute,
+}
+
+// do sends the request, retrying with backoff when it's rate limited or the server fails. Any
+// response it returns is successful (or 304 Not Modified); anything else becomes a *StatusError.
+func (sat ServerAndToken) do(req *http.Request) (*http.Response, error) {
+	return sat.doChecked(req, nil)
+}
+
+// doChecked is do, but check can turn a successful response into a *StatusError, which is
+// retried like any other. It's for GraphQL, which reports rate limits in 200 responses. check
+// has to close the body when it returns an error.
+func (sat ServerAndToken) doChecked(req *http.Request, check func(*http.Response) *StatusError,
+) (*http.Response, error) {
+	policy := defaultRetryPolicy
+	if sat.retry != nil {
+		policy = *sat.retry
+	}
+
+	ctx := req.Context()
+	apiURL := req.URL.String()
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+
+		resp, err := sat.httpClient().Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if rl, ok := parseRateLimit(resp.Header); ok && sat.RateLimit != nil {
+			*sat.RateLimit = rl
+		}
+
+		var statusErr *StatusError
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 ||
+			resp.StatusCode == http.StatusNotModified {
+			if check != nil {
+				statusErr = check(resp)
+			}
+			if statusErr == nil {
+				sat.reportCredential(true)
+				return resp, nil
+			}
+		} else {
+			statusErr = newStatusError(resp, apiURL)
+		}
+
+		if statusErr.StatusCode == http.StatusUnauthorized {
+			sat.reportCredential(false)
+		}
+		if !statusErr.Retryable() || attempt+1 >= policy.maxAttempts {
+			return nil, statusErr
+		}
+
+		wait := policy.baseDelay << attempt
+		if wait > policy.maxDelay {
+			wait = policy.maxDelay
+		}
+		switch {
+		case statusErr.RetryAfter > 0:
+			wait = statusErr.RetryAfter
+		case statusErr.RateLimit.Exhausted():
+			wait = time.Until(statusErr.RateLimit.Reset) + time.Second
+		}
+
+		if statusErr.RateLimited() && wait > policy.maxRateLimitWait {
+			return nil, statusErr
+		}
+
+		if sat.Console != nil {
+			reason := fmt.Sprintf("status %d", statusErr.StatusCode)
+			if statusErr.RateLimited() {
+				reason = "rate limited"
+			}
+			fmt.Fprintf(sat.Console, " (%s, retrying in %s)", reason, wait.Round(time.Second))
+		}
+
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
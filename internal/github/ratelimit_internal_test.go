@@ -0,0 +1,216 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fastRetries = retryPolicy{
+	maxAttempts:      3,
+	baseDelay:        time.Millisecond,
+	maxDelay:         5 * time.Millisecond,
+	maxRateLimitWait: time.Second,
+}
+
+// newFlakyServer fails the first failures requests with the handler, then succeeds.
+func newFlakyServer(t *testing.T, failures int32, fail http.HandlerFunc,
+) (*ServerAndToken, *int32) {
+	var count int32
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4321")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+
+		if atomic.AddInt32(&count, 1) <= failures {
+			fail(w, r)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, `{"login": "bloomberg", "type": "Organization", "body": %q}`, body)
+	}))
+	t.Cleanup(ts.Close)
+
+	return &ServerAndToken{
+		Server: strings.TrimPrefix(ts.URL, "https://"),
+		Client: ts.Client(),
+		retry:  &fastRetries,
+	}, &count
+}
+
+func Test_RetriesServerErrors(t *testing.T) {
+	sat, count := newFlakyServer(t, 2, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	var console bytes.Buffer
+	sat.Console = &console
+	sat.RateLimit = &RateLimit{}
+
+	acct, err := sat.GetAccount(context.Background(), "bloomberg")
+	require.NoError(t, err)
+	assert.Equal(t, "bloomberg", acct.Login)
+	assert.Equal(t, int32(3), *count)
+	assert.Equal(t, " (status 502, retrying in 0s) (status 502, retrying in 0s)", console.String())
+
+	assert.Equal(t, 5000, sat.RateLimit.Limit)
+	assert.Equal(t, 4321, sat.RateLimit.Remaining)
+}
+
+func Test_GivesUpAfterMaxAttempts(t *testing.T) {
+	sat, count := newFlakyServer(t, 10, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	_, err := sat.GetAccount(context.Background(), "bloomberg")
+
+	var statusErr *StatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+	assert.False(t, errors.Is(err, ErrRateLimited))
+	assert.Equal(t, int32(3), *count)
+}
+
+func Test_DoesNotRetryOtherErrors(t *testing.T) {
+	sat, count := newFlakyServer(t, 10, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"message": "Bad credentials"}`)
+	})
+
+	_, err := sat.GetAccount(context.Background(), "bloomberg")
+	assert.Contains(t, err.Error(), "bad status code 401 from ")
+	assert.Contains(t, err.Error(), ": Bad credentials")
+	assert.Equal(t, int32(1), *count)
+}
+
+func Test_RetriesSecondaryRateLimits(t *testing.T) {
+	sat, count := newFlakyServer(t, 1, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message": "You have exceeded a secondary rate limit."}`)
+	})
+
+	var console bytes.Buffer
+	sat.Console = &console
+
+	_, err := sat.GetAccount(context.Background(), "bloomberg")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), *count)
+	assert.Contains(t, console.String(), "rate limited")
+}
+
+func Test_GivesUpOnLongRateLimitWaits(t *testing.T) {
+	sat, count := newFlakyServer(t, 10, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	_, err := sat.GetAccount(context.Background(), "bloomberg")
+	assert.True(t, errors.Is(err, ErrRateLimited))
+	assert.Equal(t, int32(1), *count)
+
+	var statusErr *StatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.True(t, statusErr.RateLimit.Exhausted())
+}
+
+const graphQLRateLimited = `{"errors": [{"type": "RATE_LIMITED", "message": "API rate limit exceeded"}]}`
+
+func Test_RetriesGraphQLRateLimits(t *testing.T) {
+	var count int32
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) == 1 {
+			fmt.Fprint(w, graphQLRateLimited) // with a 200
+			return
+		}
+		fmt.Fprint(w, `{"data": {"viewer": {"login": "alice"}}}`)
+	}))
+	t.Cleanup(ts.Close)
+
+	var console bytes.Buffer
+	sat := ServerAndToken{
+		Server:  strings.TrimPrefix(ts.URL, "https://"),
+		Client:  ts.Client(),
+		Console: &console,
+		retry:   &fastRetries,
+	}
+
+	var data struct {
+		Viewer struct {
+			Login string `json:"login"`
+		} `json:"viewer"`
+	}
+	require.NoError(t, sat.graphQL(context.Background(), "query { viewer { login } }", nil, &data))
+	assert.Equal(t, "alice", data.Viewer.Login)
+	assert.Equal(t, int32(2), count)
+	assert.Contains(t, console.String(), "rate limited")
+}
+
+func Test_GivesUpOnLongGraphQLRateLimitWaits(t *testing.T) {
+	sat, count := newFlakyServer(t, 10, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		fmt.Fprint(w, graphQLRateLimited)
+	})
+
+	var data struct{}
+	err := sat.graphQL(context.Background(), "query { viewer { login } }", nil, &data)
+	assert.True(t, errors.Is(err, ErrRateLimited))
+	assert.Contains(t, err.Error(), "graphql error RATE_LIMITED")
+	assert.Equal(t, int32(1), *count)
+}
+
+func Test_HonorsRetryAfterAndContext(t *testing.T) {
+	sat, count := newFlakyServer(t, 10, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := sat.GetAccount(ctx, "bloomberg")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, int32(1), *count)
+}
+
+func Test_ResendsBodyOnRetry(t *testing.T) {
+	sat, _ := newFlakyServer(t, 1, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req, err := http.NewRequest(http.MethodPost, "https://"+sat.Server, strings.NewReader("hello"))
+	require.NoError(t, err)
+
+	resp, err := sat.do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"body": "hello"`)
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	assert.Equal(t, 30*time.Second, parseRetryAfter("30", now))
+	assert.Equal(t, 90*time.Second, parseRetryAfter("Tue, 01 Jun 2021 12:01:30 GMT", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+}
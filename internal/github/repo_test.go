@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package github_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mikesep/frond/internal/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RepoCustomProperties(t *testing.T) {
+	var repo github.Repo
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"name": "cli",
+		"visibility": "internal",
+		"custom_properties": {
+			"team": "tools",
+			"env": ["prod", "staging"],
+			"owner": null
+		}
+	}`), &repo))
+
+	assert.Equal(t, "internal", repo.VisibilityOrPrivate())
+	assert.Equal(t, map[string]github.PropertyValue{
+		"team":  {"tools"},
+		"env":   {"prod", "staging"},
+		"owner": nil,
+	}, repo.CustomProperties)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"custom_properties": {"size": 3}}`), &repo))
+}
+
+func Test_VisibilityOrPrivate(t *testing.T) {
+	assert.Equal(t, "private", github.Repo{Private: true}.VisibilityOrPrivate())
+	assert.Equal(t, "public", github.Repo{}.VisibilityOrPrivate())
+}
+
+func Test_GetRepoFollowsRenames(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/bloomberg/old-name", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/api/v3/repositories/42", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/api/v3/repositories/42", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token secret", r.Header.Get("Authorization"))
+		fmt.Fprint(w, `{"name": "new-name", "full_name": "tools/new-name"}`)
+	})
+
+	ts := httptest.NewTLSServer(mux)
+	defer ts.Close()
+
+	sat := github.ServerAndToken{
+		Server: strings.TrimPrefix(ts.URL, "https://"),
+		Token:  "secret",
+		Client: ts.Client(),
+	}
+
+	repo, err := sat.GetRepo(context.Background(), "bloomberg/old-name")
+	require.NoError(t, err)
+	assert.Equal(t, "tools/new-name", repo.FullName)
+
+	_, err = sat.GetRepo(context.Background(), "no-owner")
+	assert.Error(t, err)
+}
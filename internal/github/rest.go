@@ -6,12 +6,14 @@ package github
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 func (sat *ServerAndToken) restV3URL() string {
@@ -29,6 +31,7 @@ type Repo struct {
 	FullName string  `json:"full_name"`
 	Account  Account `json:"owner"`
 	CloneURL string  `json:"clone_url"`
+	SSHURL   string  `json:"ssh_url"`
 
 	Archived      bool     `json:"archived"`
 	DefaultBranch string   `json:"default_branch"`
@@ -37,6 +40,51 @@ type Repo struct {
 	Language      string   `json:"language"`
 	Private       bool     `json:"private"`
 	Topics        []string `json:"topics"`
+
+	Visibility       string                   `json:"visibility"` // public, private, or internal
+	CustomProperties map[string]PropertyValue `json:"custom_properties"`
+
+	PushedAt        time.Time `json:"pushed_at"`
+	Size            int       `json:"size"` // in KB
+	StargazersCount int       `json:"stargazers_count"`
+
+	Parent *Repo `json:"parent"` // only set for forks, and not in listings
+}
+
+// VisibilityOrPrivate is the repo's visibility. Older servers don't report it, so it's guessed
+// from Private for them.
+func (r Repo) VisibilityOrPrivate() string {
+	switch {
+	case r.Visibility != "":
+		return r.Visibility
+	case r.Private:
+		return "private"
+	default:
+		return "public"
+	}
+}
+
+// PropertyValue holds a custom property's values. Multi-select properties can have several, and
+// unset properties have none.
+type PropertyValue []string
+
+func (pv *PropertyValue) UnmarshalJSON(data []byte) error {
+	var values []string
+	if err := json.Unmarshal(data, &values); err == nil {
+		*pv = values
+		return nil
+	}
+
+	var value *string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("unexpected custom property value %s", data)
+	}
+
+	*pv = nil
+	if value != nil {
+		*pv = PropertyValue{*value}
+	}
+	return nil
 }
 
 type Account struct {
@@ -58,34 +106,19 @@ const (
 
 type RepoFilterFunc func(r Repo) bool
 
+// GetRepo follows GitHub's redirects, so a renamed or transferred repo can be found by its old
+// name. The repo that's returned has its new name.
 func (sat ServerAndToken) GetRepo(ctx context.Context, fullName string) (Repo, error) {
-	apiURL := fmt.Sprintf("%s/repos/%s", sat.restV3URL(), url.PathEscape(fullName))
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
-	if err != nil {
-		return Repo{}, err
+	parts := strings.SplitN(fullName, "/", 2)
+	if len(parts) != 2 {
+		return Repo{}, fmt.Errorf("expected owner/repo, got %q", fullName)
 	}
 
-	req.Header.Set("Authorization", "token "+sat.Token)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return Repo{}, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return Repo{}, fmt.Errorf("bad status code %d from %s", resp.StatusCode, apiURL)
-	}
-
-	respBytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return Repo{}, err
-	}
+	apiURL := fmt.Sprintf("%s/repos/%s/%s",
+		sat.restV3URL(), url.PathEscape(parts[0]), url.PathEscape(parts[1]))
 
 	var repoInfo Repo
-	if err := json.Unmarshal(respBytes, &repoInfo); err != nil {
+	if _, err := sat.getJSON(ctx, apiURL, &repoInfo); err != nil {
 		return Repo{}, err
 	}
 
@@ -108,37 +141,45 @@ func (sat ServerAndToken) ListRepos(
 	for nextURL != "" {
 		fmt.Fprint(progress, ".") // print a dot for each iteration
 
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, nextURL, nil)
+		var repos []Repo
+		header, err := sat.getJSON(ctx, nextURL, &repos)
 		if err != nil {
 			return results, err
 		}
 
-		req.Header.Set("Authorization", "token "+sat.Token)
-		req.Header.Set("Accept", "application/vnd.github.v3+json")
+		results = append(results, repos...)
 
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return results, err
-		}
-		defer resp.Body.Close()
+		nextURL = getRelFromLinkHeader(header.Get("Link"), "next")
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			return results, fmt.Errorf("bad status code: %v", resp.StatusCode)
-		}
+	return results, nil
+}
 
-		respBytes, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return results, err
-		}
+// ListTeamRepos lists the repos a team in an org has access to. The team is named by its slug.
+func (sat ServerAndToken) ListTeamRepos(ctx context.Context, progress io.Writer, org, team string,
+) ([]Repo, error) {
+	var results []Repo
+
+	nextURL := fmt.Sprintf("%s/orgs/%s/teams/%s/repos?per_page=100",
+		sat.restV3URL(), url.PathEscape(org), url.PathEscape(team))
+
+	for nextURL != "" {
+		fmt.Fprint(progress, ".") // print a dot for each iteration
 
 		var repos []Repo
-		if err := json.Unmarshal(respBytes, &repos); err != nil {
-			return nil, err
+		header, err := sat.getJSON(ctx, nextURL, &repos)
+
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			return results, fmt.Errorf("failed to find team %q in %q", team, org)
+		}
+		if err != nil {
+			return results, err
 		}
 
 		results = append(results, repos...)
 
-		nextURL = getRelFromLinkHeader(resp.Header.Get("Link"), "next")
+		nextURL = getRelFromLinkHeader(header.Get("Link"), "next")
 	}
 
 	return results, nil
@@ -149,36 +190,73 @@ func (sat ServerAndToken) GetAccount(ctx context.Context, name string) (Account,
 
 	queryURL := fmt.Sprintf("%s/users/%s", sat.restV3URL(), url.PathEscape(name))
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, queryURL, nil)
+	_, err := sat.getJSON(ctx, queryURL, &acct)
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return Account{}, fmt.Errorf("failed to find account %q", name)
+	}
+
+	return acct, err
+}
+
+//------------------------------------------------------------------------------
+
+// getJSON decodes a successful response into v, and returns its headers. When there's a cache,
+// it's used to make the request conditional.
+func (sat ServerAndToken) getJSON(ctx context.Context, apiURL string, v interface{},
+) (http.Header, error) {
+	var cached cacheEntry
+	var haveCached bool
+	if sat.Cache != nil {
+		cached, haveCached = sat.Cache.load(apiURL)
+
+		if sat.Cache.Offline {
+			if !haveCached {
+				return nil, fmt.Errorf("%w: %s", ErrNotCached, apiURL)
+			}
+			return cached.header(), json.Unmarshal(cached.Body, v)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
 	if err != nil {
-		return acct, err
+		return nil, err
 	}
 
 	req.Header.Set("Authorization", "token "+sat.Token)
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if haveCached && !sat.Cache.Refresh {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := sat.do(req)
 	if err != nil {
-		return acct, err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	switch resp.StatusCode {
-	case http.StatusOK:
-		// yay!
-	case http.StatusNotFound:
-		return acct, fmt.Errorf("failed to find account %q", name)
-	default:
-		return acct, fmt.Errorf("bad status code: %v", resp.StatusCode)
+	if resp.StatusCode == http.StatusNotModified {
+		return cached.header(), json.Unmarshal(cached.Body, v)
 	}
 
 	respBytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return acct, err
+		return nil, err
 	}
 
-	err = json.Unmarshal(respBytes, &acct)
-	return acct, err
+	if err := json.Unmarshal(respBytes, v); err != nil {
+		return nil, err
+	}
+
+	if etag := resp.Header.Get("ETag"); sat.Cache != nil && etag != "" {
+		entry := cacheEntry{ETag: etag, Link: resp.Header.Get("Link"), Body: respBytes}
+		if err := sat.Cache.store(apiURL, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp.Header, nil
 }
 
 //------------------------------------------------------------------------------
@@ -6,23 +6,86 @@ package github_test
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/mikesep/frond/internal/git"
 	"github.com/mikesep/frond/internal/github"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func Test_ListRepos(t *testing.T) {
-	cred, err := git.FillCredential("https", "github.com")
-	if err != nil {
-		panic(err)
-	}
+// useFakeCredentialHelper replaces the user's credential helpers with a script that always
+// returns the same token.
+func useFakeCredentialHelper(t *testing.T, token string) {
+	script := filepath.Join(t.TempDir(), "helper")
+	require.NoError(t, ioutil.WriteFile(script, []byte(`#!/bin/sh
+if [ "$1" = get ]; then
+	echo username=frond
+	echo password=`+token+`
+fi
+`), 0o755))
+
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "credential.helper")
+	t.Setenv("GIT_CONFIG_VALUE_0", script)
+}
+
+// newFakeRESTServer serves bloomberg's repos in two pages, and the accounts bloomberg and
+// mikesep. The token comes from a fake credential helper, like it would from a real one.
+func newFakeRESTServer(t *testing.T) github.ServerAndToken {
+	useFakeCredentialHelper(t, "s3cret")
+
+	mux := http.NewServeMux()
+
+	var ts *httptest.Server
+	mux.HandleFunc("/api/v3/orgs/bloomberg/repos", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token s3cret", r.Header.Get("Authorization"))
+		assert.Equal(t, "all", r.URL.Query().Get("type"))
+
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s&page=2>; rel="next"`, ts.URL, r.URL.String()))
+			fmt.Fprint(w, `[{"full_name": "bloomberg/bde", "language": "C++"}]`)
+			return
+		}
+		fmt.Fprint(w, `[{"full_name": "bloomberg/go-testgroup", "language": "Go"}]`)
+	})
+	mux.HandleFunc("/api/v3/users/bloomberg", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"login": "bloomberg", "type": "Organization"}`)
+	})
+	mux.HandleFunc("/api/v3/users/mikesep", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"login": "mikesep", "type": "User"}`)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Not Found"}`)
+	})
+
+	ts = httptest.NewTLSServer(mux)
+	t.Cleanup(ts.Close)
+
+	server := strings.TrimPrefix(ts.URL, "https://")
 
-	sat := github.ServerAndToken{
-		Server: "github.com",
+	cred, err := git.FillCredential("https", server)
+	require.NoError(t, err)
+
+	return github.ServerAndToken{
+		Server: server,
 		Token:  cred.Password,
+		Client: ts.Client(),
 	}
+}
+
+func Test_ListRepos(t *testing.T) {
+	sat := newFakeRESTServer(t)
 
 	ctx := context.Background()
 
@@ -30,30 +93,17 @@ func Test_ListRepos(t *testing.T) {
 
 	repos, err := sat.ListRepos(ctx, &progressBuffer,
 		github.Account{Login: "bloomberg", Type: "Organization"}, github.AllRepos)
+	require.NoError(t, err)
 
-	t.Logf("err: %+v", err)
-	t.Logf("repos len: %d ", len(repos))
-
-	limit := 200
-	if limit > len(repos) {
-		limit = len(repos)
-	}
-
-	for i, r := range repos[0:limit] {
-		t.Logf("  %2d: %s %s\n", i, r.FullName, r.Language)
-	}
+	assert.Equal(t, "..", progressBuffer.String())
+	assert.Equal(t, []github.Repo{
+		{FullName: "bloomberg/bde", Language: "C++"},
+		{FullName: "bloomberg/go-testgroup", Language: "Go"},
+	}, repos)
 }
 
 func Test_GetAccount(t *testing.T) {
-	cred, err := git.FillCredential("https", "github.com")
-	if err != nil {
-		panic(err)
-	}
-
-	sat := github.ServerAndToken{
-		Server: "github.com",
-		Token:  cred.Password,
-	}
+	sat := newFakeRESTServer(t)
 
 	ctx := context.Background()
 
@@ -3,9 +3,23 @@
 
 package github
 
+import (
+	"io"
+	"net/http"
+)
+
 type ServerAndToken struct {
 	Server string // github.com or ghes.example.com
 	Token  string
+
+	Client    *http.Client // nil means http.DefaultClient
+	Console   io.Writer    // where to say that requests are being retried; nil means nowhere
+	RateLimit *RateLimit   // when set, updated from every response
+	Cache     *Cache       // nil means no caching
+
+	GitCredential *GitCredential // when set, told whether Token worked
+
+	retry *retryPolicy // nil means defaultRetryPolicy
 }
 
 func (sat *ServerAndToken) apiURL() string {
@@ -15,3 +29,10 @@ func (sat *ServerAndToken) apiURL() string {
 
 	return "https://" + sat.Server + "/api"
 }
+
+func (sat *ServerAndToken) httpClient() *http.Client {
+	if sat.Client == nil {
+		return http.DefaultClient
+	}
+	return sat.Client
+}
@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package github_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mikesep/frond/internal/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ListTeamRepos(t *testing.T) {
+	var ts *httptest.Server
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/orgs/bloomberg/teams/tools/repos", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?per_page=100&page=2>; rel="next"`, ts.URL, r.URL.Path))
+			fmt.Fprint(w, `[{"name": "go-testgroup", "full_name": "bloomberg/go-testgroup"}]`)
+			return
+		}
+		fmt.Fprint(w, `[{"name": "frond", "full_name": "bloomberg/frond"}]`)
+	})
+
+	ts = httptest.NewTLSServer(mux)
+	defer ts.Close()
+
+	sat := github.ServerAndToken{
+		Server: strings.TrimPrefix(ts.URL, "https://"),
+		Client: ts.Client(),
+	}
+
+	var progress bytes.Buffer
+	repos, err := sat.ListTeamRepos(context.Background(), &progress, "bloomberg", "tools")
+	require.NoError(t, err)
+
+	require.Len(t, repos, 2)
+	assert.Equal(t, "bloomberg/go-testgroup", repos[0].FullName)
+	assert.Equal(t, "bloomberg/frond", repos[1].FullName)
+	assert.Equal(t, "..", progress.String())
+
+	_, err = sat.ListTeamRepos(context.Background(), &progress, "bloomberg", "nobody")
+	assert.EqualError(t, err, `failed to find team "nobody" in "bloomberg"`)
+}
@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+type Project struct {
+	ID                int       `json:"id"`
+	Name              string    `json:"name"`
+	Path              string    `json:"path"`
+	PathWithNamespace string    `json:"path_with_namespace"`
+	Namespace         Namespace `json:"namespace"`
+	HTTPURLToRepo     string    `json:"http_url_to_repo"`
+	SSHURLToRepo      string    `json:"ssh_url_to_repo"`
+
+	Archived          bool        `json:"archived"`
+	DefaultBranch     string      `json:"default_branch"`
+	ForkedFromProject *ProjectRef `json:"forked_from_project"`
+	Topics            []string    `json:"topics"`
+	Visibility        string      `json:"visibility"` // private, internal, or public
+}
+
+// Fork is true when the API said which project this one was forked from.
+func (p Project) Fork() bool {
+	return p.ForkedFromProject != nil
+}
+
+type ProjectRef struct {
+	ID                int    `json:"id"`
+	PathWithNamespace string `json:"path_with_namespace"`
+}
+
+type Namespace struct {
+	ID       int    `json:"id"`
+	Path     string `json:"path"`
+	FullPath string `json:"full_path"`
+	Kind     string `json:"kind"` // group or user
+}
+
+type Group struct {
+	ID       int    `json:"id"`
+	Path     string `json:"path"`
+	FullPath string `json:"full_path"`
+}
+
+func (sat ServerAndToken) GetGroup(ctx context.Context, fullPath string) (Group, error) {
+	var group Group
+	apiURL := fmt.Sprintf("%s/groups/%s", sat.restV4URL(), url.PathEscape(fullPath))
+	err := sat.getJSON(ctx, apiURL, &group)
+	return group, err
+}
+
+func (sat ServerAndToken) GetProject(ctx context.Context, fullPath string) (Project, error) {
+	var project Project
+	apiURL := fmt.Sprintf("%s/projects/%s", sat.restV4URL(), url.PathEscape(fullPath))
+	err := sat.getJSON(ctx, apiURL, &project)
+	return project, err
+}
+
+// ListGroupProjects lists the projects in a group, and in all of its nested subgroups when
+// includeSubgroups is set. Projects shared with the group from elsewhere are left out.
+func (sat ServerAndToken) ListGroupProjects(
+	ctx context.Context, progress io.Writer, group string, includeSubgroups bool,
+) ([]Project, error) {
+	var results []Project
+
+	baseURL := fmt.Sprintf(
+		"%s/groups/%s/projects?include_subgroups=%t&with_shared=false&per_page=100&order_by=path&sort=asc",
+		sat.restV4URL(), url.PathEscape(group), includeSubgroups)
+
+	nextPage := "1"
+
+	for nextPage != "" {
+		fmt.Fprint(progress, ".") // print a dot for each iteration
+
+		var projects []Project
+		resp, err := sat.get(ctx, baseURL+"&page="+nextPage)
+		if err != nil {
+			return results, err
+		}
+
+		err = decodeJSON(resp, &projects)
+		if err != nil {
+			return results, err
+		}
+
+		results = append(results, projects...)
+
+		nextPage = resp.Header.Get("X-Next-Page")
+	}
+
+	return results, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (sat ServerAndToken) get(ctx context.Context, apiURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("PRIVATE-TOKEN", sat.Token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := sat.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("not found: %s", apiURL)
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("bad status code %d from %s", resp.StatusCode, apiURL)
+	}
+}
+
+func (sat ServerAndToken) getJSON(ctx context.Context, apiURL string, v interface{}) error {
+	resp, err := sat.get(ctx, apiURL)
+	if err != nil {
+		return err
+	}
+
+	return decodeJSON(resp, v)
+}
+
+func decodeJSON(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(respBytes, v)
+}
@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package gitlab_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mikesep/frond/internal/gitlab"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFakeServer(t *testing.T) gitlab.ServerAndToken {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/v4/groups/platform/projects", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "true", r.URL.Query().Get("include_subgroups"))
+
+		switch r.URL.Query().Get("page") {
+		case "1":
+			w.Header().Set("X-Next-Page", "2")
+			fmt.Fprint(w, `[
+				{"id": 1, "name": "api", "path": "api", "path_with_namespace": "platform/api",
+				 "namespace": {"full_path": "platform", "kind": "group"},
+				 "http_url_to_repo": "https://gitlab.example.com/platform/api.git",
+				 "default_branch": "main", "visibility": "internal", "topics": ["go"]}
+			]`)
+		case "2":
+			fmt.Fprint(w, `[
+				{"id": 2, "name": "tools", "path": "tools", "path_with_namespace": "platform/infra/tools",
+				 "namespace": {"full_path": "platform/infra", "kind": "group"},
+				 "http_url_to_repo": "https://gitlab.example.com/platform/infra/tools.git",
+				 "default_branch": "master", "archived": true, "visibility": "private",
+				 "forked_from_project": {"id": 9, "path_with_namespace": "upstream/tools"}}
+			]`)
+		default:
+			t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+		}
+	})
+
+	mux.HandleFunc("/api/v4/groups/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.TrimPrefix(r.URL.RawPath, "/api/v4/groups/") == "platform%2Finfra" {
+			fmt.Fprint(w, `{"id": 3, "path": "infra", "full_path": "platform/infra"}`)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	ts := httptest.NewTLSServer(mux)
+	t.Cleanup(ts.Close)
+
+	return gitlab.ServerAndToken{
+		Server: strings.TrimPrefix(ts.URL, "https://"),
+		Token:  "secret",
+		Client: ts.Client(),
+	}
+}
+
+func Test_ListGroupProjects(t *testing.T) {
+	sat := newFakeServer(t)
+
+	var progress bytes.Buffer
+	projects, err := sat.ListGroupProjects(context.Background(), &progress, "platform", true)
+	require.NoError(t, err)
+
+	assert.Equal(t, "..", progress.String())
+	require.Len(t, projects, 2)
+
+	assert.Equal(t, "platform/api", projects[0].PathWithNamespace)
+	assert.Equal(t, "main", projects[0].DefaultBranch)
+	assert.Equal(t, "internal", projects[0].Visibility)
+	assert.Equal(t, []string{"go"}, projects[0].Topics)
+	assert.False(t, projects[0].Fork())
+
+	assert.Equal(t, "platform/infra", projects[1].Namespace.FullPath)
+	assert.True(t, projects[1].Archived)
+	assert.True(t, projects[1].Fork())
+}
+
+func Test_GetGroup(t *testing.T) {
+	sat := newFakeServer(t)
+
+	group, err := sat.GetGroup(context.Background(), "platform/infra")
+	require.NoError(t, err)
+	assert.Equal(t, "platform/infra", group.FullPath)
+
+	_, err = sat.GetGroup(context.Background(), "missing")
+	assert.Error(t, err)
+}
+
+func Test_bad_token(t *testing.T) {
+	sat := newFakeServer(t)
+	sat.Token = "wrong"
+
+	_, err := sat.ListGroupProjects(context.Background(), &bytes.Buffer{}, "platform", true)
+	assert.Error(t, err)
+}
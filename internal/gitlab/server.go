@@ -0,0 +1,43 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package gitlab
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/mikesep/frond/internal/probe"
+)
+
+type ServerAndToken struct {
+	Server string // gitlab.com or gitlab.example.com
+	Token  string
+
+	Client *http.Client // nil means http.DefaultClient
+}
+
+func (sat *ServerAndToken) restV4URL() string {
+	return "https://" + sat.Server + "/api/v4"
+}
+
+func (sat *ServerAndToken) httpClient() *http.Client {
+	if sat.Client == nil {
+		return http.DefaultClient
+	}
+	return sat.Client
+}
+
+// DetectServer checks for the header GitLab adds to every API response. A nil client means
+// http.DefaultClient.
+func DetectServer(ctx context.Context, client *http.Client, server string) bool {
+	if server == "gitlab.com" {
+		return true
+	}
+
+	sat := ServerAndToken{Server: server}
+	return probe.Check(ctx, client, http.MethodHead, sat.restV4URL()+"/version",
+		func(resp *http.Response) bool {
+			return resp.Header.Get("X-Gitlab-Meta") != ""
+		})
+}
@@ -0,0 +1,48 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+// Package probe asks servers what they are, for detecting which API a server speaks.
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Timeout keeps a probe from hanging on servers that never answer.
+const Timeout = 5 * time.Second
+
+// Check sends a request to url and says whether check accepts the response. Requests that fail
+// or don't finish within Timeout don't pass. A nil client means http.DefaultClient.
+func Check(ctx context.Context, client *http.Client, method, url string,
+	check func(resp *http.Response) bool,
+) bool {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return false
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	return check(resp)
+}
+
+// DecodeJSON says whether resp is OK and its body decodes into v.
+func DecodeJSON(resp *http.Response, v interface{}) bool {
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	return json.NewDecoder(resp.Body).Decode(v) == nil
+}
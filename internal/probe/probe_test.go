@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package probe_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikesep/frond/internal/bitbucket"
+	"github.com/mikesep/frond/internal/gitea"
+	"github.com/mikesep/frond/internal/gitlab"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DetectServer(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/slow/") {
+			<-r.Context().Done()
+			return
+		}
+
+		switch r.URL.Path {
+		case "/gitlab/api/v4/version":
+			w.Header().Set("X-Gitlab-Meta", `{"cid":"1"}`)
+		case "/gitea/api/v1/version":
+			fmt.Fprint(w, `{"version": "1.21.0"}`)
+		case "/gitea-broken/api/v1/version":
+			fmt.Fprint(w, `<html>`)
+		case "/bitbucket/rest/api/1.0/application-properties":
+			fmt.Fprint(w, `{"version": "8.9.0", "displayName": "Bitbucket"}`)
+		case "/other/rest/api/1.0/application-properties":
+			fmt.Fprint(w, `{"displayName": "Other"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	server := strings.TrimPrefix(ts.URL, "https://")
+
+	detectors := map[string]func(context.Context, *http.Client, string) bool{
+		"gitlab":    gitlab.DetectServer,
+		"gitea":     gitea.DetectServer,
+		"bitbucket": bitbucket.DetectServer,
+	}
+
+	testcases := []struct {
+		detector string
+		server   string
+		want     bool
+	}{
+		{"gitlab", server + "/gitlab", true},
+		{"gitlab", "gitlab.com", true}, // without asking
+		{"gitlab", server + "/gitea", false},
+		{"gitlab", server + "/slow", false},
+
+		{"gitea", server + "/gitea", true},
+		{"gitea", server + "/gitea-broken", false},
+		{"gitea", server + "/gitlab", false},
+		{"gitea", server + "/slow", false},
+
+		{"bitbucket", server + "/bitbucket", true},
+		{"bitbucket", server + "/other", false},
+		{"bitbucket", server + "/gitea", false},
+		{"bitbucket", server + "/slow", false},
+
+		{"gitea", "bad host\x7f", false},
+	}
+
+	for _, tc := range testcases {
+		// Bound the slow servers with the context, so the test doesn't wait for probe.Timeout.
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		got := detectors[tc.detector](ctx, ts.Client(), tc.server)
+		cancel()
+
+		assert.Equal(t, tc.want, got, "%s %s", tc.detector, tc.server)
+	}
+}
@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mikesep/frond/internal/auth"
+	"github.com/mikesep/frond/internal/github"
+)
+
+// authConfig says where to get a server's API token. Without one, git's credential helper is
+// used, with environment variables as a fallback for machines that don't have a helper.
+type authConfig struct {
+	Type string `yaml:"type"` // git, env, file, gh, or app
+
+	Env  []string `yaml:"env,omitempty"`  // env: the variables to try, in order
+	File string   `yaml:"file,omitempty"` // file: holds the token
+
+	AppID          int64  `yaml:"appID,omitempty"`          // app
+	InstallationID int64  `yaml:"installationID,omitempty"` // app
+	PrivateKeyFile string `yaml:"privateKeyFile,omitempty"` // app
+}
+
+// validate needs to know which kind of source the config is in, since some types of auth only
+// work with GitHub.
+func (cfg *authConfig) validate(section string) error {
+	if cfg == nil {
+		return nil
+	}
+
+	isGitHub := section == "github"
+
+	switch cfg.Type {
+	case "git", "env":
+	case "file":
+		if cfg.File == "" {
+			return fmt.Errorf("auth: file is missing")
+		}
+	case "gh":
+		if !isGitHub {
+			return fmt.Errorf("auth: gh only works with github")
+		}
+	case "app":
+		if !isGitHub {
+			return fmt.Errorf("auth: app only works with github")
+		}
+		if cfg.AppID == 0 || cfg.InstallationID == 0 || cfg.PrivateKeyFile == "" {
+			return fmt.Errorf("auth: app needs appID, installationID, and privateKeyFile")
+		}
+	default:
+		return fmt.Errorf("auth: unknown type %q (expected git, env, file, gh, or app)", cfg.Type)
+	}
+
+	return nil
+}
+
+// tokenSource needs to know which kind of source the config is in to pick default environment
+// variables.
+func (cfg *authConfig) tokenSource(section, server, syncRoot string) auth.TokenSource {
+	if cfg == nil {
+		return auth.FirstOf{
+			auth.GitCredential{Host: server},
+			auth.Env{Vars: defaultTokenVars(section, server)},
+		}
+	}
+
+	switch cfg.Type {
+	case "env":
+		vars := cfg.Env
+		if len(vars) == 0 {
+			vars = defaultTokenVars(section, server)
+		}
+		return auth.Env{Vars: vars}
+	case "file":
+		return auth.File{Path: resolveConfigPath(cfg.File, syncRoot)}
+	case "gh":
+		return auth.GHCLI{Host: server}
+	case "app":
+		return auth.GitHubApp{
+			Server:         server,
+			AppID:          cfg.AppID,
+			InstallationID: cfg.InstallationID,
+			PrivateKeyFile: resolveConfigPath(cfg.PrivateKeyFile, syncRoot),
+		}
+	}
+
+	return auth.GitCredential{Host: server}
+}
+
+func (cfg *authConfig) token(section, server string, listing listingOptions) (string, error) {
+	token, err := cfg.tokenSource(section, server, listing.syncRoot).Token(context.Background())
+	if err != nil {
+		return "", fmt.Errorf("failed to get a token for %s: %w", server, err)
+	}
+	return token, nil
+}
+
+// gitHubToken is token, but when the token comes from git's credential helpers, it also returns
+// the credential so the GitHub client can tell the helpers whether it worked.
+func (cfg *authConfig) gitHubToken(server string, listing listingOptions,
+) (string, *github.GitCredential, error) {
+	if cfg != nil && cfg.Type != "git" {
+		token, err := cfg.token("github", server, listing)
+		return token, nil, err
+	}
+
+	ctx := context.Background()
+
+	cred, err := auth.GitCredential{Host: server}.Fill(ctx)
+	if err == nil {
+		return cred.Password, &github.GitCredential{Credential: cred}, nil
+	}
+
+	if cfg == nil {
+		envToken, envErr := auth.Env{Vars: defaultTokenVars("github", server)}.Token(ctx)
+		if envErr == nil {
+			return envToken, nil, nil
+		}
+		err = fmt.Errorf("%w; %v", err, envErr)
+	}
+	return "", nil, fmt.Errorf("failed to get a token for %s: %w", server, err)
+}
+
+// identity tells apart listings made with different auth configs. Unlike a token, it doesn't
+// change when the token is refreshed, and it's known without asking for a token.
+func (cfg *authConfig) identity() string {
+	if cfg == nil {
+		return "default"
+	}
+	return fmt.Sprintf("%+v", *cfg)
+}
+
+// defaultTokenVars are the ones each server's CLI uses. For GitHub, that's gh.
+func defaultTokenVars(section, server string) []string {
+	switch section {
+	case "gitlab":
+		return []string{"GITLAB_TOKEN"}
+	case "gitea":
+		return []string{"GITEA_TOKEN"}
+	case "bitbucket":
+		return []string{"BITBUCKET_TOKEN"}
+	}
+
+	if server == "github.com" {
+		return []string{"GH_TOKEN", "GITHUB_TOKEN"}
+	}
+	return []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+}
+
+// resolveConfigPath expands environment variables and ~, and makes relative paths relative to
+// the sync root.
+func resolveConfigPath(path, syncRoot string) string {
+	path = os.ExpandEnv(path)
+
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(syncRoot, path)
+	}
+
+	return filepath.Clean(path)
+}
@@ -0,0 +1,161 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bloomberg/go-testgroup"
+	"github.com/mikesep/frond/internal/auth"
+)
+
+func Test_Auth(t *testing.T) {
+	testgroup.RunSerially(t, &authTests{}) // some tests set environment variables
+}
+
+type authTests struct{}
+
+func (*authTests) Decode_auth_per_server(t *testgroup.T) {
+	cfg, err := parseConfig(strings.NewReader(`
+sources:
+- dir: github.com
+  github:
+    server: github.com
+    org: bloomberg
+    auth:
+      type: app
+      appID: 1234
+      installationID: 42
+      privateKeyFile: keys/app.pem
+- dir: gitlab.example.com
+  gitlab:
+    server: gitlab.example.com
+    group: platform
+    auth:
+      type: env
+      env: [CI_JOB_TOKEN]
+`))
+	t.Require.NoError(err)
+
+	sources := cfg.sources()
+	t.Equal(auth.GitHubApp{
+		Server:         "github.com",
+		AppID:          1234,
+		InstallationID: 42,
+		PrivateKeyFile: filepath.Join("/sync", "keys", "app.pem"),
+	}, sources[0].auth().tokenSource("github", "github.com", "/sync"))
+	t.Equal(auth.Env{Vars: []string{"CI_JOB_TOKEN"}},
+		sources[1].auth().tokenSource("gitlab", "gitlab.example.com", "/sync"))
+}
+
+func (*authTests) Default_is_git_credential_then_env(t *testgroup.T) {
+	var cfg *authConfig
+	t.NoError(cfg.validate("github"))
+	t.Equal(auth.FirstOf{
+		auth.GitCredential{Host: "github.com"},
+		auth.Env{Vars: []string{"GH_TOKEN", "GITHUB_TOKEN"}},
+	}, cfg.tokenSource("github", "github.com", "/sync"))
+	t.Equal(auth.FirstOf{
+		auth.GitCredential{Host: "gitlab.example.com"},
+		auth.Env{Vars: []string{"GITLAB_TOKEN"}},
+	}, cfg.tokenSource("gitlab", "gitlab.example.com", "/sync"))
+}
+
+func (*authTests) Reject_bad_auth(t *testgroup.T) {
+	for section, cfg := range map[string]authConfig{
+		"github":    {Type: "magic"},
+		"gitlab":    {Type: "gh"},
+		"bitbucket": {Type: "file"},
+		"gitea":     {Type: "app", AppID: 1, InstallationID: 2, PrivateKeyFile: "x"},
+	} {
+		cfg := cfg
+		t.Error(cfg.validate(section), "%s %+v", section, cfg)
+	}
+
+	t.Error((&authConfig{Type: "app", AppID: 1}).validate("github"))
+	t.Error((&authConfig{Type: "file"}).validate("github"))
+}
+
+func (*authTests) Env_defaults_match_gh(t *testgroup.T) {
+	cfg := &authConfig{Type: "env"}
+	t.Equal(auth.Env{Vars: []string{"GH_TOKEN", "GITHUB_TOKEN"}}, cfg.tokenSource("github", "github.com", ""))
+	t.Equal(auth.Env{Vars: []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}},
+		cfg.tokenSource("github", "ghes.example.com", ""))
+
+	t.Setenv("GH_TOKEN", "")
+	t.Setenv("GITHUB_TOKEN", "from-env")
+	token, err := cfg.token("github", "github.com", listingOptions{})
+	t.NoError(err)
+	t.Equal("from-env", token)
+}
+
+func (*authTests) Config_paths_are_resolved(t *testgroup.T) {
+	home, err := os.UserHomeDir()
+	t.Require.NoError(err)
+
+	t.Setenv("FROND_TEST_DIR", "/secrets")
+
+	t.Equal(filepath.Join("/sync", "token"), resolveConfigPath("token", "/sync"))
+	t.Equal(filepath.Join(home, ".frond-token"), resolveConfigPath("~/.frond-token", "/sync"))
+	t.Equal(filepath.Join("/secrets", "token"), resolveConfigPath("$FROND_TEST_DIR/token", "/sync"))
+	t.Equal(filepath.Join("/abs", "token"), resolveConfigPath("/abs/token", "/sync"))
+}
+
+func (*authTests) Only_git_credentials_are_reported(t *testgroup.T) {
+	t.Setenv("GH_TOKEN", "from-env")
+
+	token, gitCredential, err := (&authConfig{Type: "env"}).gitHubToken("github.com", listingOptions{})
+	t.NoError(err)
+	t.Equal("from-env", token)
+	t.Nil(gitCredential)
+}
+
+func (*authTests) GitHub_token_is_only_filled_once(t *testgroup.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "helper.log")
+	helper := filepath.Join(dir, "helper")
+	t.Require.NoError(os.WriteFile(helper, []byte(`#!/bin/sh
+echo "$1" >> "`+log+`"
+echo username=frond
+echo password=s3cret
+`), 0o755))
+
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "credential.helper")
+	t.Setenv("GIT_CONFIG_VALUE_0", helper)
+
+	cfg := &gitHubConfig{Server: "github.example.com"}
+	for i := 0; i < 2; i++ {
+		token, gitCredential, err := cfg.cachedToken(listingOptions{})
+		t.Require.NoError(err)
+		t.Equal("s3cret", token)
+		t.NotNil(gitCredential)
+	}
+
+	calls, err := os.ReadFile(log)
+	t.Require.NoError(err)
+	t.Equal("get\n", string(calls))
+}
+
+func (*authTests) Init_auth_matches_sync(t *testgroup.T) {
+	opts := InitOptions{}
+	cfg, err := opts.authConfig("gitlab")
+	t.NoError(err)
+	t.Nil(cfg)
+
+	opts.Auth = "env"
+	cfg, err = opts.authConfig("gitlab")
+	t.NoError(err)
+	t.Equal(&authConfig{Type: "env"}, cfg)
+	t.Equal(auth.Env{Vars: []string{"GITLAB_TOKEN"}}, cfg.tokenSource("gitlab", "gitlab.example.com", ""))
+
+	opts.Auth = "gh"
+	_, err = opts.authConfig("gitea")
+	t.Error(err)
+}
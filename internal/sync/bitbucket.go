@@ -0,0 +1,333 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mikesep/frond/internal/bitbucket"
+)
+
+type bitbucketConfig struct {
+	Server string `yaml:"server"`
+
+	SingleProject string                              `yaml:"project,omitempty"`
+	Projects      map[string]*bitbucketConfigCriteria `yaml:"projects,omitempty"`
+
+	bitbucketConfigCriteria `yaml:",inline"`
+
+	CloneProtocol string `yaml:"cloneProtocol,omitempty"` // https (default) or ssh
+
+	Auth *authConfig `yaml:"auth,omitempty"`
+}
+
+type bitbucketConfigCriteria struct {
+	Names []string `yaml:"names,omitempty"`
+
+	Archived *bool `yaml:"archived,omitempty"`
+	Fork     *bool `yaml:"fork,omitempty"`
+	Public   *bool `yaml:"public,omitempty"`
+
+	names filter // compiled from Names by compileFilters
+}
+
+//------------------------------------------------------------------------------
+
+func (cfg *bitbucketConfig) validate() error {
+	if cfg.Server == "" {
+		return fmt.Errorf("server is missing")
+	}
+
+	switch {
+	case cfg.SingleProject != "" && len(cfg.Projects) != 0:
+		return fmt.Errorf("cannot have project and projects")
+	case cfg.SingleProject == "" && len(cfg.Projects) == 0:
+		return fmt.Errorf("project or projects is missing")
+	}
+
+	if err := cfg.compileFilters(); err != nil {
+		return err
+	}
+
+	if err := validateCloneProtocol(cfg.CloneProtocol); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// compileFilters compiles the filters of every set of criteria, which validate also does.
+func (cfg *bitbucketConfig) compileFilters() error {
+	criteria := []*bitbucketConfigCriteria{&cfg.bitbucketConfigCriteria}
+	for _, c := range cfg.Projects {
+		if c != nil {
+			criteria = append(criteria, c)
+		}
+	}
+
+	for _, c := range criteria {
+		var err error
+		if c.names, err = compileFieldFilter("names", c.Names, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cfg bitbucketConfig) projectKeys() []string {
+	if cfg.SingleProject != "" {
+		return []string{cfg.SingleProject}
+	}
+
+	keys := make([]string, 0, len(cfg.Projects))
+	for key := range cfg.Projects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// bitbucketCloneLinkName is the name Bitbucket gives to clone links for the protocol.
+func bitbucketCloneLinkName(protocol string) string {
+	if protocol == "ssh" {
+		return "ssh"
+	}
+	return "http"
+}
+
+// pathForRepo puts each repo in a dir named after its project, unless there's only one project.
+func (cfg bitbucketConfig) pathForRepo(repo bitbucket.Repo) string {
+	if cfg.SingleProject != "" {
+		return repo.Slug
+	}
+	return filepath.Join(repo.Project.Key, repo.Slug)
+}
+
+func (cfg bitbucketConfig) filterRepo(repo bitbucket.Repo) (rejectionReason string) {
+	projectCriteria := cfg.Projects[repo.Project.Key]
+
+	names, namesFilter := cfg.Names, cfg.names
+	if projectCriteria != nil && len(projectCriteria.Names) > 0 {
+		names, namesFilter = projectCriteria.Names, projectCriteria.names
+	}
+	if !namesFilter.matches(repo.Slug) {
+		return fmt.Sprintf("%s doesn't match any name in %v", repo.Slug, names)
+	}
+
+	archived := cfg.Archived
+	if projectCriteria != nil && projectCriteria.Archived != nil {
+		archived = projectCriteria.Archived
+	}
+	if archived != nil && repo.Archived != *archived {
+		isOrIsNot := "is"
+		if !repo.Archived {
+			isOrIsNot = "is not"
+		}
+		return fmt.Sprintf("repo %s archived", isOrIsNot)
+	}
+
+	fork := cfg.Fork
+	if projectCriteria != nil && projectCriteria.Fork != nil {
+		fork = projectCriteria.Fork
+	}
+	if fork != nil && repo.Fork() != *fork {
+		isOrIsNot := "is"
+		if !repo.Fork() {
+			isOrIsNot = "is not"
+		}
+		return fmt.Sprintf("repo %s a fork", isOrIsNot)
+	}
+
+	public := cfg.Public
+	if projectCriteria != nil && projectCriteria.Public != nil {
+		public = projectCriteria.Public
+	}
+	if public != nil && repo.Public != *public {
+		isOrIsNot := "is"
+		if !repo.Public {
+			isOrIsNot = "is not"
+		}
+		return fmt.Sprintf("repo %s public", isOrIsNot)
+	}
+
+	return ""
+}
+
+//------------------------------------------------------------------------------
+
+func findBitbucketRepos(
+	syncRoot, workDir string, cmdArgs []string, cfg *bitbucketConfig, listing listingOptions,
+	console io.Writer,
+) (idealRepoMap, rejectionReasonMap, error) {
+	token, err := cfg.Auth.token("bitbucket", cfg.Server, listing)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bbSAT := bitbucket.ServerAndToken{
+		Server: cfg.Server,
+		Token:  token,
+	}
+
+	return findBitbucketReposWith(syncRoot, workDir, cmdArgs, cfg, bbSAT, console)
+}
+
+func findBitbucketReposWith(
+	syncRoot, workDir string, cmdArgs []string, cfg *bitbucketConfig,
+	bbSAT bitbucket.ServerAndToken, console io.Writer,
+) (idealRepoMap, rejectionReasonMap, error) {
+	ctx := context.Background()
+
+	absTargets, err := absTargetsInsideRoot(syncRoot, workDir, cmdArgs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	protocol, err := preferredCloneProtocol(cfg.CloneProtocol)
+	if err != nil {
+		return nil, nil, err
+	}
+	linkName := bitbucketCloneLinkName(protocol)
+
+	var unfilteredRepos []bitbucket.Repo
+
+	for _, key := range cfg.projectKeys() {
+		fmt.Fprintf(console, "Finding repos in %s/projects/%s..", cfg.Server, key)
+		rr, err := bbSAT.ListProjectRepos(ctx, console, key)
+		if err != nil {
+			fmt.Fprintf(console, " FAILED!\n")
+			return nil, nil, err
+		}
+		fmt.Fprintf(console, " found %d.\n", len(rr))
+
+		unfilteredRepos = append(unfilteredRepos, rr...)
+	}
+
+	idealRepos := idealRepoMap{}
+	rejectedRepos := rejectionReasonMap{}
+
+	for _, r := range unfilteredRepos {
+		cloneURL := r.CloneURL(linkName)
+		if cloneURL == "" {
+			return nil, nil, fmt.Errorf("%s/%s has no %s clone link",
+				r.Project.Key, r.Slug, linkName)
+		}
+
+		compURL, err := comparableRepoURL(cloneURL)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		absPath := filepath.Join(syncRoot, cfg.pathForRepo(r))
+		if !isUnderAnyTarget(absPath, absTargets) {
+			continue
+		}
+
+		if reason := cfg.filterRepo(r); reason != "" {
+			rejectedRepos[compURL] = reason
+			continue
+		}
+
+		pathToRepo, err := filepath.Rel(workDir, absPath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// The listing doesn't include default branches, so only look them up for kept repos.
+		defaultBranch, err := bbSAT.DefaultBranch(ctx, r.Project.Key, r.Slug)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		idealRepos[compURL] = idealRepo{
+			Path:          pathToRepo,
+			URL:           cloneURL,
+			DefaultBranch: defaultBranch,
+		}
+	}
+
+	fmt.Fprintf(console, "Filtered out %d and kept %d.\n", len(rejectedRepos), len(idealRepos))
+
+	return idealRepos, rejectedRepos, nil
+}
+
+//------------------------------------------------------------------------------
+
+// newBitbucketConfig accepts project URLs (BB/projects/KEY) and repo URLs
+// (BB/projects/KEY/repos/SLUG, with anything after the slug ignored).
+func newBitbucketConfig(server string, args []string, token string) (*bitbucketConfig, error) {
+	cfg := &bitbucketConfig{
+		Server:   server,
+		Projects: map[string]*bitbucketConfigCriteria{},
+	}
+
+	bbSAT := bitbucket.ServerAndToken{
+		Server: cfg.Server,
+		Token:  token,
+	}
+
+	ctx := context.Background()
+
+	for _, arg := range args {
+		u, err := url.Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %w", arg, err)
+		}
+
+		parts := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+		isProject := len(parts) == 2 && parts[0] == "projects"
+		isRepo := len(parts) >= 4 && parts[0] == "projects" && parts[2] == "repos"
+		if !isProject && !isRepo {
+			return nil, fmt.Errorf("expected BB/projects/KEY or BB/projects/KEY/repos/SLUG (%q)", arg)
+		}
+
+		project, err := bbSAT.GetProject(ctx, parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		if isProject {
+			if cfg.Projects[project.Key] != nil {
+				return nil, fmt.Errorf("cannot init both at project and repo level for %q",
+					project.Key)
+			}
+			cfg.Projects[project.Key] = nil
+			continue
+		}
+
+		repo, err := bbSAT.GetRepo(ctx, project.Key, parts[3])
+		if err != nil {
+			return nil, err
+		}
+
+		criteria, ok := cfg.Projects[project.Key]
+		if ok && criteria == nil {
+			return nil, fmt.Errorf("cannot init both at project and repo level for %q",
+				project.Key)
+		}
+		if !ok {
+			criteria = &bitbucketConfigCriteria{}
+			cfg.Projects[project.Key] = criteria
+		}
+		criteria.Names = append(criteria.Names, repo.Slug)
+	}
+
+	if len(cfg.Projects) == 1 {
+		for key, criteria := range cfg.Projects {
+			cfg.SingleProject = key
+			if criteria != nil {
+				cfg.bitbucketConfigCriteria = *criteria
+			}
+			delete(cfg.Projects, key)
+		}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bloomberg/go-testgroup"
+	"github.com/mikesep/frond/internal/bitbucket"
+)
+
+func Test_Bitbucket(t *testing.T) {
+	testgroup.RunInParallel(t, &bitbucketTests{})
+}
+
+type bitbucketTests struct{}
+
+func newFakeBitbucketServer(t *testgroup.T) bitbucket.ServerAndToken {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/rest/api/1.0/projects/TOOLS/repos", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"isLastPage": true, "values": [
+			{"slug": "cli", "project": {"key": "TOOLS"}, "links": {"clone": [
+				{"href": "https://bitbucket.example.com/scm/tools/cli.git", "name": "http"},
+				{"href": "ssh://git@bitbucket.example.com:7999/tools/cli.git", "name": "ssh"}]}},
+			{"slug": "cli-fork", "project": {"key": "TOOLS"}, "origin": {"slug": "cli"},
+			 "links": {"clone": [
+				{"href": "https://bitbucket.example.com/scm/tools/cli-fork.git", "name": "http"},
+				{"href": "ssh://git@bitbucket.example.com:7999/tools/cli-fork.git", "name": "ssh"}]}}
+		]}`)
+	})
+	mux.HandleFunc("/rest/api/1.0/projects/TOOLS/repos/cli/default-branch",
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"displayId": "develop"}`)
+		})
+
+	ts := httptest.NewTLSServer(mux)
+	t.Cleanup(ts.Close)
+
+	return bitbucket.ServerAndToken{
+		Server: strings.TrimPrefix(ts.URL, "https://"),
+		Client: ts.Client(),
+	}
+}
+
+func (*bitbucketTests) Repos_are_filtered_and_use_the_configured_clone_link(t *testgroup.T) {
+	bbSAT := newFakeBitbucketServer(t)
+
+	cfg := &bitbucketConfig{
+		Server:        "bitbucket.example.com",
+		Projects:      map[string]*bitbucketConfigCriteria{"TOOLS": {Fork: &falseVar}},
+		CloneProtocol: "ssh",
+	}
+
+	syncRoot := t.TempDir()
+	ideal, rejected, err := findBitbucketReposWith(
+		syncRoot, syncRoot, nil, cfg, bbSAT, ioutil.Discard)
+	t.Require.NoError(err)
+
+	t.Equal(idealRepoMap{
+		"bitbucket.example.com/tools/cli": {
+			Path:          filepath.Join("TOOLS", "cli"),
+			URL:           "ssh://git@bitbucket.example.com:7999/tools/cli.git",
+			DefaultBranch: "develop",
+		},
+	}, ideal)
+	t.Equal(rejectionReasonMap{
+		"bitbucket.example.com/tools/cli-fork": "repo is a fork",
+	}, rejected)
+}
+
+func (*bitbucketTests) Single_project_paths_are_just_slugs(t *testgroup.T) {
+	cfg := bitbucketConfig{SingleProject: "TOOLS"}
+	t.Equal("cli", cfg.pathForRepo(bitbucket.Repo{Slug: "cli", Project: bitbucket.Project{Key: "TOOLS"}}))
+}
@@ -4,32 +4,36 @@
 package sync
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path"
 	"path/filepath"
-	"runtime"
 	"sort"
 	"strings"
-	"sync"
 
+	"github.com/mikesep/frond/internal/action"
 	"github.com/mikesep/frond/internal/git"
 	giturls "github.com/whilp/git-urls"
-	"golang.org/x/term"
 )
 
 type Options struct {
 	Init InitOptions `command:"init" description:"Create a sync config."`
 
-	DryRun    bool `short:"n" long:"dry-run" description:"Print actions instead of doing them."`
-	Jobs      *int `short:"j" long:"jobs" value-name:"N" description:"Run up to N actions in parallel. (default: NumCPU)"`
-	KeepGoing bool `short:"k" long:"keep-going" description:"Keep going even if an action fails."`
-	Prune     bool `short:"p" long:"prune" description:"Remove extra repositories."`
+	DryRun    bool        `short:"n" long:"dry-run" description:"Print actions instead of doing them."`
+	Jobs      action.Jobs `short:"j" long:"jobs" value-name:"N" description:"Run up to N actions in parallel. (default: NumCPU)"`
+	KeepGoing bool        `short:"k" long:"keep-going" description:"Keep going even if an action fails."`
+	Prune     bool        `short:"p" long:"prune" description:"Remove extra repositories without unsynced work."`
+	Reset     bool        `long:"reset" description:"Switch clean repos to their default branch and reset pushed branches to their upstreams."`
 
-	// TODO --reset to force back to default and fast-forward branches to tracking
+	ForcePrune bool   `long:"force-prune" description:"Remove extra repositories even if they have unsynced work."`
+	TrashDir   string `long:"trash-dir" value-name:"DIR" description:"Move removed repositories into DIR (outside the sync root) instead of deleting them."`
+
+	Refresh bool `long:"refresh" description:"Download repository listings again instead of revalidating cached ones."`
+	Offline bool `long:"offline" description:"Find repositories using only cached listings."`
+
+	RewriteRemotes bool `long:"rewrite-remotes" description:"Switch existing repos' remotes to the configured clone protocol."`
 }
 
 func (opts *Options) Execute(args []string) error {
@@ -38,98 +42,42 @@ func (opts *Options) Execute(args []string) error {
 		return err
 	}
 
-	actions, err := buildActionList(workDir, args, os.Stderr)
-	if err != nil {
-		return err
+	if opts.Refresh && opts.Offline {
+		return fmt.Errorf("cannot use --refresh with --offline")
 	}
 
-	maxNameLen := 0
-	for _, a := range actions {
-		if ln := len(a.Name()); ln > maxNameLen {
-			maxNameLen = ln
+	if opts.TrashDir != "" {
+		// A missing config is reported by buildActionList.
+		if cfgPath, err := findConfigFile(workDir); err == nil {
+			if err := checkTrashDir(opts.TrashDir, filepath.Dir(cfgPath)); err != nil {
+				return err
+			}
 		}
 	}
 
-	var output reporter
-	if term.IsTerminal(int(os.Stdout.Fd())) && !opts.DryRun {
-		output = newSerializingReporter(newANSIReporter(os.Stdout, len(actions), maxNameLen))
-	} else {
-		output = newSerializingReporter(newPlainReporter(os.Stdout, len(actions), maxNameLen))
-	}
-	output.DrawInitial()
-
-	queue := make(chan syncAction)
-
-	var wg sync.WaitGroup
-	workerCtx, cancelWorkers := context.WithCancel(context.Background())
-	workers := runtime.NumCPU()
-	if opts.Jobs != nil {
-		workers = *opts.Jobs
-	}
-
-	wg.Add(workers)
-	for i := 0; i < workers; i++ {
-		go actionWorker(workerCtx, cancelWorkers, &wg, queue, opts, output)
-	}
-
-	enqueuedAll := enqueueActions(workerCtx, actions, queue)
-	close(queue)
-	wg.Wait()
+	listing := listingOptions{refresh: opts.Refresh, offline: opts.Offline}
 
-	var note string
-	if !enqueuedAll {
-		note = "Stopped early due to failures. (Use --keep-going to keep going.)"
+	syncActions, err := buildActionList(workDir, args, listing, os.Stderr)
+	if err != nil {
+		return err
 	}
-	output.Done(note)
 
-	if c := output.NumFailed(); c > 0 {
-		return fmt.Errorf("%d FAILED", c)
+	actions := make([]action.Action, 0, len(syncActions))
+	for _, a := range syncActions {
+		actions = append(actions, boundAction{syncAction: a, opts: opts})
 	}
 
-	return nil
+	return action.RunAndReport(os.Stdout, actions, opts.Jobs, opts.KeepGoing, opts.DryRun)
 }
 
-func actionWorker(
-	ctx context.Context, cancelWorkers context.CancelFunc, wg *sync.WaitGroup,
-	queue <-chan syncAction, opts *Options, output reporter,
-) {
-	defer wg.Done()
-
-	for {
-		select {
-		case <-ctx.Done(): // cancelled
-			return
-
-		case action := <-queue:
-			if action == nil { // channel closed
-				return
-			}
-
-			event := action.Do(opts)
-			output.HandleEvent(event)
-			if event.Type == actionFailed && !opts.KeepGoing {
-				cancelWorkers()
-				return
-			}
-		}
-	}
+// boundAction lets a syncAction run as an action.Action with the command's options.
+type boundAction struct {
+	syncAction
+	opts *Options
 }
 
-func enqueueActions(ctx context.Context, actions []syncAction, queue chan<- syncAction) bool {
-	enqueuedAll := false
-
-	for i, action := range actions {
-		select {
-		case <-ctx.Done():
-			return enqueuedAll
-		case queue <- action:
-			if i == len(actions)-1 {
-				enqueuedAll = true
-			}
-		}
-	}
-
-	return enqueuedAll
+func (a boundAction) Do() action.Event {
+	return a.syncAction.Do(a.opts)
 }
 
 //------------------------------------------------------------------------------
@@ -137,13 +85,27 @@ func enqueueActions(ctx context.Context, actions []syncAction, queue chan<- sync
 type idealRepo struct {
 	Path          string
 	URL           string
-	DefaultBranch string
+	DefaultBranch string // empty means the remote's HEAD
+	CloneBranch   string // empty means the remote's HEAD
+	UpstreamURL   string // a fork's parent, which becomes the upstream remote
+
+	// findUpstreamURL looks up UpstreamURL when the listing didn't include it. It's only called
+	// when it's needed, since it takes a request per fork.
+	findUpstreamURL func() (string, error)
+}
+
+func (ideal idealRepo) upstreamURL() (string, error) {
+	if ideal.findUpstreamURL == nil {
+		return ideal.UpstreamURL, nil
+	}
+	return ideal.findUpstreamURL()
 }
 
 type idealRepoMap map[string]idealRepo    // comparable URL -> repoAtPath
 type rejectionReasonMap map[string]string // comparable URL -> rejection reason
 
-func buildActionList(workDir string, cmdArgs []string, console io.Writer) ([]syncAction, error) {
+func buildActionList(workDir string, cmdArgs []string, listing listingOptions, console io.Writer,
+) ([]syncAction, error) {
 	if !filepath.IsAbs(workDir) {
 		panic(fmt.Sprintf("workDir is not absolute: %q", workDir))
 	}
@@ -162,6 +124,7 @@ func buildActionList(workDir string, cmdArgs []string, console io.Writer) ([]syn
 	}
 
 	syncRoot := filepath.Dir(cfgPath)
+	listing.syncRoot = syncRoot
 
 	localRepos, err := findRelativeLocalRepos(syncRoot, workDir, cmdArgs, console)
 	if err != nil {
@@ -172,16 +135,45 @@ func buildActionList(workDir string, cmdArgs []string, console io.Writer) ([]syn
 	// 	fmt.Printf("%d: %v\n", i, r)
 	// }
 
-	idealRepos := idealRepoMap{}
-	rejectionReasons := rejectionReasonMap{}
+	repos := newSourcedRepos()
+	var resolvers []movedRepoResolver
+
+	for _, src := range cfg.sources() {
+		sourceRoot := filepath.Join(syncRoot, filepath.FromSlash(src.Dir))
+
+		sourceArgs, ok := argsForSource(sourceRoot, workDir, cmdArgs)
+		if !ok {
+			continue
+		}
 
-	if cfg.GitHub != nil {
-		idealRepos, rejectionReasons, err = findGitHubRepos(
-			syncRoot, workDir, cmdArgs, cfg.GitHub, console)
+		ideal, rejected, err := src.findRepos(sourceRoot, workDir, sourceArgs, listing, console)
 		if err != nil {
 			return nil, err
 		}
+
+		if err := repos.add(src.String(), ideal, rejected); err != nil {
+			return nil, err
+		}
+
+		if resolve := src.movedRepoResolver(listing); resolve != nil {
+			resolvers = append(resolvers, resolve)
+		}
+	}
+
+	staticRepos, err := findStaticRepos(syncRoot, workDir, cmdArgs, cfg.Repos)
+	if err != nil {
+		return nil, err
+	}
+	if err := repos.add("repos", staticRepos, nil); err != nil {
+		return nil, err
+	}
+
+	if err := repos.checkPathCollisions(); err != nil {
+		return nil, err
 	}
+
+	idealRepos, rejectionReasons := repos.ideal, repos.rejected
+
 	// fmt.Printf("DEBUG: idealRepos:\n")
 	// for k, v := range idealRepos {
 	// 	fmt.Printf("%v: %v\n", k, v)
@@ -190,18 +182,27 @@ func buildActionList(workDir string, cmdArgs []string, console io.Writer) ([]syn
 	var actions []syncAction
 
 	for _, r := range localRepos {
-		action, err := matchRepoToAction(r, idealRepos, rejectionReasons)
+		a, err := matchRepoToAction(r, idealRepos, rejectionReasons,
+			firstMovedRepoResolver(resolvers), cfg.remoteName())
 		if err != nil {
 			return nil, err
 		}
 
-		actions = append(actions, action)
+		actions = append(actions, a)
 	}
 
 	for _, rap := range idealRepos {
+		upstreamURL, err := rap.upstreamURL()
+		if err != nil {
+			return nil, err
+		}
+
 		actions = append(actions, actionCloneRepo{
-			URL:  rap.URL,
-			Path: rap.Path,
+			URL:         rap.URL,
+			Path:        rap.Path,
+			Branch:      rap.CloneBranch,
+			RemoteName:  cfg.remoteName(),
+			UpstreamURL: upstreamURL,
 		})
 	}
 
@@ -278,8 +279,11 @@ func findRelativeLocalRepos(syncRoot, workDir string, cmdArgs []string, console
 	return repos, nil
 }
 
-// This removes repos from idealRepos as they're matched!
+// This removes repos from idealRepos as they're matched! Repos that don't match are checked with
+// resolve, if it isn't nil, in case they were renamed or transferred. When several remotes
+// match, the primary remote wins over the others, and the others win over the upstream remote.
 func matchRepoToAction(repoPath string, idealRepos idealRepoMap, rejectionReasons rejectionReasonMap,
+	resolve movedRepoResolver, primaryRemote string,
 ) (syncAction, error) {
 	localRepo := git.LocalRepo{Root: repoPath}
 
@@ -318,6 +322,26 @@ func matchRepoToAction(repoPath string, idealRepos idealRepoMap, rejectionReason
 		}
 	}
 
+	// A fork's clone matches its parent too when both are synced.
+	if len(remoteMatches) > 1 {
+		var preferred []remoteMatch
+		for _, m := range remoteMatches {
+			if m.RemoteName == primaryRemote {
+				preferred = append(preferred, m)
+			}
+		}
+		if len(preferred) == 0 {
+			for _, m := range remoteMatches {
+				if m.RemoteName != upstreamRemoteName {
+					preferred = append(preferred, m)
+				}
+			}
+		}
+		if len(preferred) > 0 {
+			remoteMatches = preferred
+		}
+	}
+
 	switch len(remoteMatches) {
 	case 0:
 		if matchedRejectedURL != "" {
@@ -327,6 +351,11 @@ func matchRepoToAction(repoPath string, idealRepos idealRepoMap, rejectionReason
 			}, nil
 		}
 
+		moved, err := findMovedRepo(localRepo, remotes, idealRepos, rejectionReasons, resolve)
+		if err != nil || moved != nil {
+			return moved, err
+		}
+
 		return actionRemoveRepo{
 			Path:   localRepo.Root,
 			Reason: "did not match any remote repo URL",
@@ -338,19 +367,41 @@ func matchRepoToAction(repoPath string, idealRepos idealRepoMap, rejectionReason
 		ideal := idealRepos[matchedRemote.ComparableURL]
 		delete(idealRepos, matchedRemote.ComparableURL)
 
-		defaultTrackingBranch := fmt.Sprintf("%s/%s", matchedRemote.RemoteName, ideal.DefaultBranch)
+		defaultTrackingBranch, err := defaultTrackingBranch(localRepo, matchedRemote.RemoteName, ideal)
+		if err != nil {
+			return nil, err
+		}
+
+		// Only the protocol can differ, since the comparable URLs match.
+		var rewrite remoteRewrite
+		if isSSHURL(remotes[matchedRemote.RemoteName].FetchURL) != isSSHURL(ideal.URL) {
+			rewrite = remoteRewrite{
+				RemoteName:   matchedRemote.RemoteName,
+				RemoteURL:    ideal.URL,
+				ProtocolOnly: true,
+			}
+		}
+
+		upstreamURL, err := upstreamToAdd(remotes, ideal)
+		if err != nil {
+			return nil, err
+		}
 
 		if localRepo.Root != ideal.Path {
 			return actionMoveAndSyncRepo{
 				OrigPath:              localRepo.Root,
 				DestPath:              ideal.Path,
 				DefaultTrackingBranch: defaultTrackingBranch,
+				remoteRewrite:         rewrite,
+				UpstreamURL:           upstreamURL,
 			}, nil
 		}
 
 		return actionSyncRepo{
 			Path:                  localRepo.Root,
 			DefaultTrackingBranch: defaultTrackingBranch,
+			remoteRewrite:         rewrite,
+			UpstreamURL:           upstreamURL,
 		}, nil
 
 	default:
@@ -359,78 +410,141 @@ func matchRepoToAction(repoPath string, idealRepos idealRepoMap, rejectionReason
 	}
 }
 
+// defaultTrackingBranch is like origin/main. When the server didn't say what the default branch
+// is, the remote's HEAD is used.
+func defaultTrackingBranch(localRepo git.LocalRepo, remoteName string, ideal idealRepo,
+) (string, error) {
+	defaultBranch := ideal.DefaultBranch
+	if defaultBranch == "" {
+		var err error
+		defaultBranch, err = localRepo.RemoteDefaultBranch(remoteName)
+		if err != nil {
+			return "", err
+		}
+		if defaultBranch == "" {
+			return "", fmt.Errorf("%s: cannot tell the default branch of %s (set a branch in %s)",
+				localRepo.Root, remoteName, syncConfigFile)
+		}
+	}
+
+	return fmt.Sprintf("%s/%s", remoteName, defaultBranch), nil
+}
+
 //------------------------------------------------------------------------------
 
 type syncAction interface {
 	Name() string
-	Do(opts *Options) actionEvent
+	Do(opts *Options) action.Event
 }
 
 type actionCloneRepo struct {
-	URL  string
-	Path string
+	URL         string
+	Path        string
+	Branch      string // empty means the remote's HEAD
+	RemoteName  string // empty means git's default
+	UpstreamURL string // added as the upstream remote
 }
 
 func (a actionCloneRepo) Name() string {
 	return a.Path
 }
 
-func (a actionCloneRepo) Do(opts *Options) actionEvent {
+func (a actionCloneRepo) Do(opts *Options) action.Event {
 	if _, err := os.Stat(a.Path); err == nil || !os.IsNotExist(err) {
-		return actionEvent{
-			Type:    actionFailed,
+		return action.Event{
+			Type:    action.Failed,
 			Name:    a.Path,
 			Message: fmt.Sprintf("would clone from %s, but %s already exists", a.URL, a.Path),
 		}
 	}
 
 	if opts.DryRun {
-		return actionEvent{
-			Type:    actionCloned,
+		return action.Event{
+			Type:    action.Cloned,
 			Name:    a.Path,
 			Message: fmt.Sprintf("would clone from %s", a.URL),
 		}
 	}
 
-	return cloneRepo(a.URL, a.Path)
+	return cloneRepo(a)
 }
 
 type actionMoveAndSyncRepo struct {
 	OrigPath              string
 	DestPath              string
 	DefaultTrackingBranch string
+
+	remoteRewrite
+	UpstreamURL string // added as the upstream remote before syncing
 }
 
 func (a actionMoveAndSyncRepo) Name() string {
 	return a.DestPath
 }
 
-func (a actionMoveAndSyncRepo) Do(opts *Options) actionEvent {
-	if _, err := os.Stat(a.DestPath); err != nil || !os.IsNotExist(err) {
-		return actionEvent{
-			Type:    actionFailed,
+func (a actionMoveAndSyncRepo) Do(opts *Options) action.Event {
+	move := a.OrigPath != a.DestPath
+
+	if _, err := os.Stat(a.DestPath); move && (err == nil || !os.IsNotExist(err)) {
+		return action.Event{
+			Type:    action.Failed,
 			Name:    a.OrigPath,
 			Message: fmt.Sprintf("would move to %s, but it already exists", a.DestPath),
 		}
 	}
 
 	if opts.DryRun {
-		return actionEvent{
-			Type:    actionUpdated,
+		var steps []string
+		if a.wanted(opts) {
+			steps = append(steps, a.describe())
+		}
+		if a.UpstreamURL != "" {
+			steps = append(steps, fmt.Sprintf("add %s remote %s", upstreamRemoteName, a.UpstreamURL))
+		}
+		if move {
+			steps = append(steps, fmt.Sprintf("move to %s", a.DestPath))
+		}
+
+		return action.Event{
+			Type:    action.Updated,
 			Name:    a.DestPath,
-			Message: fmt.Sprintf("would move to %s and sync", a.DestPath),
+			Message: dryRunMessage(steps, "sync"),
 		}
 	}
 
-	if err := os.Rename(a.OrigPath, a.DestPath); err != nil {
-		return actionEvent{
-			Type:    actionFailed,
+	if err := a.rewrite(a.OrigPath, opts); err != nil {
+		return action.Event{
+			Type:    action.Failed,
 			Name:    a.OrigPath,
 			Message: err.Error(),
 		}
 	}
 
-	return syncRepo(a.DestPath, a.DefaultTrackingBranch)
+	if a.UpstreamURL != "" {
+		if err := addUpstreamRemote(a.OrigPath, a.UpstreamURL); err != nil {
+			return action.Event{
+				Type:    action.Failed,
+				Name:    a.OrigPath,
+				Message: err.Error(),
+			}
+		}
+	}
+
+	if move {
+		err := os.MkdirAll(filepath.Dir(a.DestPath), 0o755)
+		if err == nil {
+			err = os.Rename(a.OrigPath, a.DestPath)
+		}
+		if err != nil {
+			return action.Event{
+				Type:    action.Failed,
+				Name:    a.OrigPath,
+				Message: err.Error(),
+			}
+		}
+	}
+
+	return syncRepo(a.DestPath, a.DefaultTrackingBranch, opts.Reset)
 }
 
 type actionRemoveRepo struct {
@@ -442,77 +556,199 @@ func (a actionRemoveRepo) Name() string {
 	return a.Path
 }
 
-func (a actionRemoveRepo) Do(opts *Options) actionEvent {
-	if opts.DryRun {
-		if opts.Prune {
-			return actionEvent{
-				Type:    actionRemoved,
-				Name:    a.Path,
-				Message: fmt.Sprintf("would remove: %s", a.Reason),
-			}
+func (a actionRemoveRepo) Do(opts *Options) action.Event {
+	prune := opts.Prune || opts.ForcePrune
+
+	if !prune {
+		message := fmt.Sprintf("keeping extra repo: %s -- use --prune to remove it", a.Reason)
+		if opts.DryRun {
+			message = fmt.Sprintf("would not remove without --prune: %s", a.Reason)
 		}
 
-		return actionEvent{
-			Type:    actionIgnored,
+		return action.Event{
+			Type:    action.Ignored,
 			Name:    a.Path,
-			Message: fmt.Sprintf("would not remove without --prune: %s", a.Reason),
+			Message: message,
 		}
 	}
 
-	if !opts.Prune {
-		return actionEvent{
-			Type:    actionIgnored,
+	var caveats []string
+
+	unsynced, err := findUnsyncedWork(a.Path)
+	if err != nil {
+		return action.Event{
+			Type:    action.Failed,
 			Name:    a.Path,
-			Message: fmt.Sprintf("keeping extra repo: %s -- use --prune to remove it", a.Reason),
+			Message: fmt.Sprintf("failed to check for unsynced work: %v", err),
 		}
 	}
+	if len(unsynced) > 0 {
+		if !opts.ForcePrune {
+			return action.Event{
+				Type: action.Ignored,
+				Name: a.Path,
+				Message: fmt.Sprintf("not removing since it has %s -- use --force-prune to remove it anyway",
+					strings.Join(unsynced, "; ")),
+			}
+		}
+
+		caveats = append(caveats, fmt.Sprintf("lost %s", strings.Join(unsynced, "; ")))
+	}
+
+	verb := "remove"
+	if opts.TrashDir != "" {
+		verb = "move to " + opts.TrashDir
+	}
+
+	if opts.DryRun {
+		return action.Event{
+			Type:    action.Removed,
+			Name:    a.Path,
+			Message: fmt.Sprintf("would %s: %s", verb, a.Reason),
+			Caveats: caveats,
+		}
+	}
+
+	if opts.TrashDir != "" {
+		dest, err := moveToTrash(a.Path, opts.TrashDir)
+		if err != nil {
+			return action.Event{
+				Type:    action.Failed,
+				Name:    a.Path,
+				Message: err.Error(),
+			}
+		}
 
-	// TODO check for unpushed work on branches that don't line up with their tracking branches
+		return action.Event{
+			Type:    action.Removed,
+			Name:    a.Path,
+			Message: fmt.Sprintf("moved to %s", dest),
+			Caveats: caveats,
+		}
+	}
 
-	err := os.RemoveAll(a.Path)
+	err = os.RemoveAll(a.Path)
 	if err != nil {
-		return actionEvent{
-			Type:    actionFailed,
+		return action.Event{
+			Type:    action.Failed,
 			Name:    a.Path,
 			Message: err.Error(),
 		}
 	}
 
-	return actionEvent{
-		Type:    actionRemoved,
+	return action.Event{
+		Type:    action.Removed,
 		Name:    a.Path,
 		Message: "removed",
+		Caveats: caveats,
 	}
 }
 
 type actionSyncRepo struct {
 	Path                  string
 	DefaultTrackingBranch string
+
+	remoteRewrite
+	UpstreamURL string // added as the upstream remote before syncing
 }
 
 func (a actionSyncRepo) Name() string {
 	return a.Path
 }
 
-func (a actionSyncRepo) Do(opts *Options) actionEvent {
+func (a actionSyncRepo) Do(opts *Options) action.Event {
 	if opts.DryRun {
-		return actionEvent{
-			Type:    actionUpdated,
+		var steps []string
+		if a.wanted(opts) {
+			steps = append(steps, a.describe())
+		}
+		if a.UpstreamURL != "" {
+			steps = append(steps, fmt.Sprintf("add %s remote %s", upstreamRemoteName, a.UpstreamURL))
+		}
+
+		last := "sync"
+		if opts.Reset {
+			last = fmt.Sprintf("sync and reset to %s", a.DefaultTrackingBranch)
+		}
+
+		return action.Event{
+			Type:    action.Updated,
+			Name:    a.Path,
+			Message: dryRunMessage(steps, last),
+		}
+	}
+
+	if err := a.rewrite(a.Path, opts); err != nil {
+		return action.Event{
+			Type:    action.Failed,
 			Name:    a.Path,
-			Message: "would sync",
+			Message: err.Error(),
 		}
 	}
 
-	return syncRepo(a.Path, a.DefaultTrackingBranch)
+	if a.UpstreamURL != "" {
+		if err := addUpstreamRemote(a.Path, a.UpstreamURL); err != nil {
+			return action.Event{
+				Type:    action.Failed,
+				Name:    a.Path,
+				Message: err.Error(),
+			}
+		}
+	}
+
+	return syncRepo(a.Path, a.DefaultTrackingBranch, opts.Reset)
+}
+
+// dryRunMessage is like "would point origin at URL, move to DIR, and sync".
+func dryRunMessage(steps []string, last string) string {
+	if len(steps) == 0 {
+		return "would " + last
+	}
+	return fmt.Sprintf("would %s, and %s", strings.Join(steps, ", "), last)
+}
+
+// remoteRewrite points a remote at a new URL before a repo is synced. Renamed repos always need
+// it, but switching protocols is only done with --rewrite-remotes.
+type remoteRewrite struct {
+	RemoteName   string
+	RemoteURL    string // empty means there's nothing to do
+	ProtocolOnly bool
+}
+
+func (r remoteRewrite) wanted(opts *Options) bool {
+	return r.RemoteURL != "" && (!r.ProtocolOnly || opts.RewriteRemotes)
+}
+
+func (r remoteRewrite) describe() string {
+	return fmt.Sprintf("point %s at %s", r.RemoteName, r.RemoteURL)
+}
+
+func (r remoteRewrite) rewrite(repoPath string, opts *Options) error {
+	if !r.wanted(opts) {
+		return nil
+	}
+
+	localRepo := git.LocalRepo{Root: repoPath}
+	if err := localRepo.SetRemoteURL(r.RemoteName, r.RemoteURL); err != nil {
+		return fmt.Errorf("failed to %s: %w", r.describe(), err)
+	}
+	return nil
 }
 
 //------------------------------------------------------------------------------
 
+// comparableRepoURL is the same for a repo's HTTPS and SSH URLs, so it ignores ports, and the
+// /scm/ that Bitbucket Server puts in front of its HTTP paths.
 func comparableRepoURL(rawURL string) (string, error) {
 	u, err := giturls.Parse(rawURL)
 	if err != nil {
 		return "", err
 	}
 
-	return path.Join(u.Host, strings.TrimSuffix(u.Path, ".git")), nil
+	repoPath := strings.TrimSuffix(u.Path, ".git")
+	if u.Scheme == "http" || u.Scheme == "https" {
+		repoPath = strings.TrimPrefix(repoPath, "/scm/")
+	}
+
+	return path.Join(u.Hostname(), repoPath), nil
 }
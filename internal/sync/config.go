@@ -16,6 +16,9 @@ import (
 
 const syncConfigFile = "frond.sync.yaml"
 
+// cacheDir is where API responses are cached, relative to the sync root.
+var cacheDir = filepath.Join(".frond", "cache")
+
 var errNoConfigFileFound = fmt.Errorf("no sync config file found")
 
 // workDir should be absolute
@@ -50,8 +53,26 @@ func findConfigFile(workDir string) (string, error) {
 
 //------------------------------------------------------------------------------
 
+// syncConfig has either a single source at the top level, or a list of sources. Either way, it
+// can also list individual repos.
 type syncConfig struct {
-	GitHub *gitHubConfig `yaml:"github"`
+	sourceConfig `yaml:",inline"`
+
+	Sources []sourceConfig `yaml:"sources,omitempty"`
+
+	Repos []staticRepoConfig `yaml:"repos,omitempty"`
+
+	RemoteName string `yaml:"remoteName,omitempty"` // default: origin
+}
+
+func (cfg syncConfig) sources() []sourceConfig {
+	if len(cfg.Sources) > 0 {
+		return cfg.Sources
+	}
+	if cfg.sourceConfig.isEmpty() {
+		return nil
+	}
+	return []sourceConfig{cfg.sourceConfig}
 }
 
 func parseConfig(r io.Reader) (syncConfig, error) {
@@ -114,9 +135,30 @@ func writeConfig(cfg syncConfig) error {
 //------------------------------------------------------------------------------
 
 func validateConfig(cfg syncConfig) error {
-	if cfg.GitHub != nil {
-		return cfg.GitHub.validate()
+	if err := validateStaticRepos(cfg.Repos); err != nil {
+		return err
+	}
+
+	if err := validateRemoteName(cfg.RemoteName); err != nil {
+		return err
+	}
+
+	if len(cfg.Sources) == 0 {
+		if cfg.sourceConfig.isEmpty() && len(cfg.Repos) > 0 {
+			return nil
+		}
+		return cfg.sourceConfig.validate()
+	}
+
+	if !cfg.sourceConfig.isEmpty() {
+		return fmt.Errorf("cannot have sources and a top-level %v", cfg.sourceConfig.sections())
+	}
+
+	for i, src := range cfg.Sources {
+		if err := src.validate(); err != nil {
+			return fmt.Errorf("sources[%d]: %w", i, err)
+		}
 	}
 
-	return fmt.Errorf("empty config")
+	return nil
 }
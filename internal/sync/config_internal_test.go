@@ -111,3 +111,217 @@ github:
 	t.Require.NotNil(apache.Exclude)
 	t.Equal([]string{"zookeeper"}, apache.Exclude.Names)
 }
+
+func (grp *syncConfigTests) Decode_gitlab_groups(t *testgroup.T) {
+	cfg, err := parseConfig(strings.NewReader(`
+gitlab:
+  server: gitlab.example.com
+  groups:
+    platform:
+    data/pipelines:
+      topics: [etl]
+  archived: false
+  visibility: [private, internal]
+  subgroups: false
+`))
+	t.Require.NoError(err)
+
+	t.Nil(cfg.GitHub)
+	t.Require.NotNil(cfg.GitLab)
+	gl := cfg.GitLab
+
+	t.Equal("gitlab.example.com", gl.Server)
+	t.Equal([]string{"data/pipelines", "platform"}, gl.groupNames())
+	t.Require.NotNil(gl.Groups["data/pipelines"])
+	t.Equal([]string{"etl"}, gl.Groups["data/pipelines"].Topics)
+	t.Equal(&falseVar, gl.Archived)
+	t.Equal([]string{"private", "internal"}, gl.Visibility)
+	t.False(gl.includeSubgroups())
+}
+
+func (grp *syncConfigTests) Reject_bad_gitlab_visibility(t *testgroup.T) {
+	_, err := parseConfig(strings.NewReader(`
+gitlab:
+  server: gitlab.example.com
+  group: platform
+  visibility: [secret]
+`))
+	t.Error(err)
+}
+
+func (grp *syncConfigTests) Decode_bitbucket_projects(t *testgroup.T) {
+	cfg, err := parseConfig(strings.NewReader(`
+bitbucket:
+  server: bitbucket.example.com
+  projects:
+    TOOLS:
+    LEGACY:
+      names: [api-*]
+  archived: false
+  cloneProtocol: ssh
+`))
+	t.Require.NoError(err)
+
+	t.Require.NotNil(cfg.Bitbucket)
+	bb := cfg.Bitbucket
+
+	t.Equal("bitbucket.example.com", bb.Server)
+	t.Equal([]string{"LEGACY", "TOOLS"}, bb.projectKeys())
+	t.Require.NotNil(bb.Projects["LEGACY"])
+	t.Equal([]string{"api-*"}, bb.Projects["LEGACY"].Names)
+	t.Equal(&falseVar, bb.Archived)
+	t.Equal("ssh", bb.CloneProtocol)
+}
+
+func (grp *syncConfigTests) Reject_more_than_one_section(t *testgroup.T) {
+	_, err := parseConfig(strings.NewReader(`
+github:
+  server: github.com
+  org: bloomberg
+bitbucket:
+  server: bitbucket.example.com
+  project: TOOLS
+`))
+	t.Error(err)
+}
+
+func (grp *syncConfigTests) Decode_multiple_sources(t *testgroup.T) {
+	cfg, err := parseConfig(strings.NewReader(`
+sources:
+- dir: github.com
+  github:
+    server: github.com
+    org: bloomberg
+- dir: gitlab.example.com
+  gitlab:
+    server: gitlab.example.com
+    group: platform
+`))
+	t.Require.NoError(err)
+
+	sources := cfg.sources()
+	t.Require.Len(sources, 2)
+	t.Equal("github.com", sources[0].Dir)
+	t.Require.NotNil(sources[0].GitHub)
+	t.Equal("bloomberg", sources[0].GitHub.SingleOrg)
+	t.Equal("gitlab.example.com", sources[1].Dir)
+	t.Require.NotNil(sources[1].GitLab)
+}
+
+func (grp *syncConfigTests) Reject_sources_with_a_top_level_source(t *testgroup.T) {
+	_, err := parseConfig(strings.NewReader(`
+github:
+  server: github.com
+  org: bloomberg
+sources:
+- gitlab:
+    server: gitlab.example.com
+    group: platform
+`))
+	t.Error(err)
+}
+
+func (grp *syncConfigTests) Reject_source_dir_outside_sync_root(t *testgroup.T) {
+	_, err := parseConfig(strings.NewReader(`
+sources:
+- dir: ../elsewhere
+  github:
+    server: github.com
+    org: bloomberg
+`))
+	t.Error(err)
+}
+
+func (grp *syncConfigTests) Reject_teams_for_users(t *testgroup.T) {
+	_, err := parseConfig(strings.NewReader(`
+github:
+  server: github.com
+  users:
+    mikesep:
+      teams: [tools]
+`))
+	t.Error(err)
+
+	_, err = parseConfig(strings.NewReader(`
+github:
+  server: github.com
+  user: mikesep
+  exclude:
+    teams: [tools]
+`))
+	t.Error(err)
+}
+
+func (grp *syncConfigTests) Reject_teams_for_gitea(t *testgroup.T) {
+	_, err := parseConfig(strings.NewReader(`
+gitea:
+  server: gitea.example.com
+  orgs:
+    tools:
+      teams: [owners]
+`))
+	t.Error(err)
+}
+
+func (grp *syncConfigTests) Decode_visibility_and_properties(t *testgroup.T) {
+	cfg, err := parseConfig(strings.NewReader(`
+github:
+  server: ghes.example.com
+  org: bloomberg
+  visibility: [internal]
+  properties:
+    team: [tools]
+`))
+	t.Require.NoError(err)
+
+	t.Equal([]string{"internal"}, cfg.GitHub.Visibility)
+	t.Equal(map[string][]string{"team": {"tools"}}, cfg.GitHub.Properties)
+}
+
+func (grp *syncConfigTests) Reject_bad_github_visibility_and_properties(t *testgroup.T) {
+	for _, yaml := range []string{`
+github:
+  server: github.com
+  org: bloomberg
+  exclude:
+    visibility: [secret]
+`, `
+github:
+  server: github.com
+  org: bloomberg
+  api: graphql
+  properties:
+    team: [tools]
+`, `
+gitea:
+  server: gitea.example.com
+  org: tools
+  properties:
+    team: [tools]
+`} {
+		_, err := parseConfig(strings.NewReader(yaml))
+		t.Error(err, yaml)
+	}
+}
+
+func (grp *syncConfigTests) Reject_bad_pushed_within(t *testgroup.T) {
+	_, err := parseConfig(strings.NewReader(`
+github:
+  server: github.com
+  org: bloomberg
+  pushedWithin: 3 years
+`))
+	t.Error(err)
+}
+
+func (grp *syncConfigTests) Reject_activity_exclusions(t *testgroup.T) {
+	_, err := parseConfig(strings.NewReader(`
+github:
+  server: github.com
+  orgs:
+    bloomberg:
+      exclude:
+        minStars: 10
+`))
+	t.Error(err)
+}
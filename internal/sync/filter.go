@@ -4,41 +4,125 @@
 package sync
 
 import (
+	"fmt"
 	"path/filepath"
+	"regexp"
+	"strings"
 )
 
-func matchesAnyFilter(word string, filters []string) (bool, error) {
-	if len(filters) == 0 {
-		return true, nil
-	}
+// filter is a compiled list of patterns. Patterns are globs like filepath.Match uses, or regular
+// expressions when they start with re:. A pattern starting with ! is negated: words it matches
+// are rejected even if another pattern matches them.
+type filter struct {
+	include []pattern
+	exclude []pattern
+
+	ignoreCase bool
+}
+
+type pattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+func compileFilter(patterns []string, ignoreCase bool) (filter, error) {
+	f := filter{ignoreCase: ignoreCase}
+
+	for _, s := range patterns {
+		negated := strings.HasPrefix(s, "!")
+		if negated {
+			s = s[1:]
+		}
 
-	for _, filter := range filters {
-		matched, err := filepath.Match(filter, word)
-		if err != nil {
-			return false, err
+		var p pattern
+		if strings.HasPrefix(s, "re:") {
+			expr := s[len("re:"):]
+			if ignoreCase {
+				expr = "(?i)" + expr
+			}
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return filter{}, fmt.Errorf("bad regular expression %q: %w", s, err)
+			}
+			p.re = re
+		} else {
+			if ignoreCase {
+				s = strings.ToLower(s)
+			}
+			if _, err := filepath.Match(s, ""); err != nil {
+				return filter{}, fmt.Errorf("bad pattern %q: %w", s, err)
+			}
+			p.glob = s
 		}
-		if matched {
-			return true, nil
+
+		if negated {
+			f.exclude = append(f.exclude, p)
+		} else {
+			f.include = append(f.include, p)
 		}
 	}
 
-	return false, nil
+	return f, nil
+}
+
+// matches says whether the word matches an included pattern (or there are only negated ones),
+// and doesn't match a negated pattern.
+func (f filter) matches(word string) bool {
+	return f.matchesAny([]string{word})
 }
 
-func anyWordMatchesAnyFilter(words []string, filters []string) (bool, error) {
-	if len(filters) == 0 {
-		return true, nil
+// matchesAny is like matches, but for a set of words like topics. None of them can match a
+// negated pattern, and at least one has to match an included pattern.
+func (f filter) matchesAny(words []string) bool {
+	for _, word := range words {
+		if f.anyPatternMatches(f.exclude, word) {
+			return false
+		}
+	}
+
+	if len(f.include) == 0 {
+		return true
 	}
 
 	for _, word := range words {
-		matched, err := matchesAnyFilter(word, filters)
-		if err != nil {
-			return false, err
+		if f.anyPatternMatches(f.include, word) {
+			return true
 		}
-		if matched {
-			return true, nil
+	}
+
+	return false
+}
+
+func (f filter) anyPatternMatches(patterns []pattern, word string) bool {
+	if f.ignoreCase {
+		word = strings.ToLower(word)
+	}
+
+	for _, p := range patterns {
+		if p.re != nil {
+			if p.re.MatchString(word) {
+				return true
+			}
+			continue
+		}
+
+		if matched, _ := filepath.Match(p.glob, word); matched { // checked by compileFilter
+			return true
 		}
 	}
 
-	return false, nil
+	return false
+}
+
+//------------------------------------------------------------------------------
+
+// compileFieldFilter is compileFilter for a config field, so errors say which field is bad. The
+// criteria compile their filters when the config is validated, so bad patterns are caught when
+// the config is loaded instead of in the middle of a sync, and each is only compiled once.
+func compileFieldFilter(field string, patterns []string, ignoreCase bool) (filter, error) {
+	f, err := compileFilter(patterns, ignoreCase)
+	if err != nil {
+		return filter{}, fmt.Errorf("%s: %w", field, err)
+	}
+	return f, nil
 }
@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bloomberg/go-testgroup"
+)
+
+func Test_filter(t *testing.T) {
+	testgroup.RunInParallel(t, &filterTests{})
+}
+
+type filterTests struct{}
+
+func (*filterTests) Globs_regexps_and_negations(t *testgroup.T) {
+	f, err := compileFilter([]string{"re:^svc-(api|worker)-", "tool-*", "!*-archive"}, false)
+	t.Require.NoError(err)
+
+	for word, want := range map[string]bool{
+		"svc-api-users":      true,
+		"svc-worker-email":   true,
+		"svc-web-frontend":   false,
+		"tool-lint":          true,
+		"tool-lint-archive":  false,
+		"svc-api-v1-archive": false,
+		"Tool-lint":          false, // case matters
+	} {
+		t.Equal(want, f.matches(word), word)
+	}
+}
+
+func (*filterTests) Only_negations(t *testgroup.T) {
+	f, err := compileFilter([]string{"!deprecated", "!re:^old"}, false)
+	t.Require.NoError(err)
+
+	t.True(f.matches("cli"))
+	t.False(f.matches("deprecated"))
+	t.False(f.matches("oldschool"))
+
+	t.True(f.matchesAny(nil))
+	t.True(f.matchesAny([]string{"go", "cli"}))
+	t.False(f.matchesAny([]string{"go", "deprecated"}))
+}
+
+func (*filterTests) Words_need_one_match_and_no_negated_match(t *testgroup.T) {
+	f, err := compileFilter([]string{"go", "!experimental"}, false)
+	t.Require.NoError(err)
+
+	t.True(f.matchesAny([]string{"cli", "go"}))
+	t.False(f.matchesAny([]string{"cli"}))
+	t.False(f.matchesAny([]string{"go", "experimental"}))
+	t.False(f.matchesAny(nil))
+}
+
+func (*filterTests) Empty_matches_everything(t *testgroup.T) {
+	f, err := compileFilter(nil, false)
+	t.Require.NoError(err)
+
+	t.True(f.matches(""))
+	t.True(f.matchesAny(nil))
+}
+
+func (*filterTests) Languages_ignore_case(t *testgroup.T) {
+	for _, filters := range [][]string{{"go"}, {"GO"}, {"re:^go$"}, {"G*"}} {
+		c := gitHubConfigCriteria{Languages: filters}
+		t.Require.NoError(c.compileFilters())
+		t.True(c.languages.matches("Go"), "%v", filters)
+	}
+
+	c := gitHubConfigCriteria{Languages: []string{"!go"}, Names: []string{"go"}}
+	t.Require.NoError(c.compileFilters())
+	t.False(c.languages.matches("Go"))
+	t.False(c.names.matches("Go"), "only languages ignore case")
+}
+
+func (*filterTests) Bad_patterns_fail_config_validation(t *testgroup.T) {
+	for _, criteria := range []string{
+		"names: [\"re:(\"]",
+		"topics: [\"[\"]",
+		"languages: [\"!re:*\"]",
+		"exclude: {names: [\"re:[\"]}",
+		"properties: {team: [\"re:)\"]}",
+	} {
+		_, err := parseConfig(strings.NewReader(`
+github:
+  server: github.com
+  org: bloomberg
+  ` + criteria + `
+`))
+		t.Error(err, criteria)
+	}
+
+	_, err := parseConfig(strings.NewReader(`
+gitlab:
+  server: gitlab.example.com
+  groups:
+    platform:
+      names: ["re:("]
+`))
+	t.Error(err)
+
+	_, err = parseConfig(strings.NewReader(`
+bitbucket:
+  server: bitbucket.example.com
+  project: KEY
+  names: ["["]
+`))
+	t.Error(err)
+}
+
+func (*filterTests) Parsing_the_config_compiles_the_filters(t *testgroup.T) {
+	cfg, err := parseConfig(strings.NewReader(`
+github:
+  server: github.com
+  orgs:
+    bloomberg:
+      names: ["re:^go-"]
+      exclude: {topics: ["deprecated"]}
+`))
+	t.Require.NoError(err)
+
+	criteria := cfg.GitHub.Orgs["bloomberg"]
+	t.True(criteria.names.matches("go-testgroup"))
+	t.False(criteria.names.matches("blazingmq"))
+	t.True(criteria.Exclude.topics.matches("deprecated"))
+}
@@ -0,0 +1,293 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/mikesep/frond/internal/gitea"
+	"github.com/mikesep/frond/internal/github"
+)
+
+// Gitea (and Forgejo) orgs, users, and repos work just like GitHub's, so the gitea section of
+// the config is turned into a gitHubConfig, and repos are converted to github.Repos so that
+// they're filtered and placed on disk the same way. The section only has the fields that Gitea
+// supports, though, so there are no teams, properties, or api.
+type giteaConfig struct {
+	Server string `yaml:"server"`
+
+	SingleOrg string                                        `yaml:"org,omitempty"`
+	Orgs      map[string]*giteaConfigCriteriaWithExclusions `yaml:"orgs,omitempty"`
+
+	SingleUser string                                        `yaml:"user,omitempty"`
+	Users      map[string]*giteaConfigCriteriaWithExclusions `yaml:"users,omitempty"`
+
+	giteaConfigCriteriaWithExclusions `yaml:",inline"`
+
+	SingleDirForAllRepos   *bool   `yaml:"singleDirForAllRepos,omitempty"`
+	AccountPrefixSeparator *string `yaml:"accountPrefixSeparator,omitempty"`
+
+	Auth *authConfig `yaml:"auth,omitempty"`
+
+	CloneProtocol string `yaml:"cloneProtocol,omitempty"` // https (default) or ssh
+
+	asGitHub *gitHubConfig // set up by validate
+}
+
+type giteaConfigCriteriaWithExclusions struct {
+	giteaConfigCriteria `yaml:",inline"`
+	Exclude             *giteaConfigCriteria `yaml:"exclude,omitempty"`
+}
+
+type giteaConfigCriteria struct {
+	Names     []string `yaml:"names,omitempty"`
+	Topics    []string `yaml:"topics,omitempty"`
+	Languages []string `yaml:"languages,omitempty"`
+
+	Archived   *bool `yaml:"archived,omitempty"`
+	Fork       *bool `yaml:"fork,omitempty"`
+	IsTemplate *bool `yaml:"is_template,omitempty"`
+	Private    *bool `yaml:"private,omitempty"`
+
+	Visibility []string `yaml:"visibility,omitempty"` // public, private, or internal
+
+	// Gitea doesn't say when a repo was last pushed to, so pushedWithin goes by when it was last
+	// updated, which also counts changes like new issues.
+	PushedWithin string `yaml:"pushedWithin,omitempty"`
+	MaxSizeMB    *int   `yaml:"maxSizeMB,omitempty"`
+	MinStars     *int   `yaml:"minStars,omitempty"`
+}
+
+// validate checks the config as a gitHubConfig, and keeps that (with its filters compiled) for
+// finding repos.
+func (cfg *giteaConfig) validate() error {
+	gh := cfg.toGitHub()
+	if err := gh.validate(); err != nil {
+		return err
+	}
+	cfg.asGitHub = gh
+	return nil
+}
+
+func (cfg *giteaConfig) toGitHub() *gitHubConfig {
+	gh := &gitHubConfig{
+		Server:                             cfg.Server,
+		SingleOrg:                          cfg.SingleOrg,
+		SingleUser:                         cfg.SingleUser,
+		gitHubConfigCriteriaWithExclusions: *cfg.giteaConfigCriteriaWithExclusions.toGitHub(),
+		SingleDirForAllRepos:               cfg.SingleDirForAllRepos,
+		AccountPrefixSeparator:             cfg.AccountPrefixSeparator,
+		Auth:                               cfg.Auth,
+		CloneProtocol:                      cfg.CloneProtocol,
+	}
+
+	if cfg.Orgs != nil {
+		gh.Orgs = map[string]*gitHubConfigCriteriaWithExclusions{}
+		for name, c := range cfg.Orgs {
+			gh.Orgs[name] = c.toGitHub()
+		}
+	}
+	if cfg.Users != nil {
+		gh.Users = map[string]*gitHubConfigCriteriaWithExclusions{}
+		for name, c := range cfg.Users {
+			gh.Users[name] = c.toGitHub()
+		}
+	}
+
+	return gh
+}
+
+func (c *giteaConfigCriteriaWithExclusions) toGitHub() *gitHubConfigCriteriaWithExclusions {
+	if c == nil {
+		return nil
+	}
+	return &gitHubConfigCriteriaWithExclusions{
+		gitHubConfigCriteria: *c.giteaConfigCriteria.toGitHub(),
+		Exclude:              c.Exclude.toGitHub(),
+	}
+}
+
+func (c *giteaConfigCriteria) toGitHub() *gitHubConfigCriteria {
+	if c == nil {
+		return nil
+	}
+	return &gitHubConfigCriteria{
+		Names:        c.Names,
+		Topics:       c.Topics,
+		Languages:    c.Languages,
+		Archived:     c.Archived,
+		Fork:         c.Fork,
+		IsTemplate:   c.IsTemplate,
+		Private:      c.Private,
+		Visibility:   c.Visibility,
+		PushedWithin: c.PushedWithin,
+		MaxSizeMB:    c.MaxSizeMB,
+		MinStars:     c.MinStars,
+	}
+}
+
+// newGiteaAccounts converts the accounts that newConfigFromAccounts found, which only narrow
+// them down by name.
+func newGiteaAccounts(accounts map[string]*gitHubConfigCriteriaWithExclusions,
+) map[string]*giteaConfigCriteriaWithExclusions {
+	if accounts == nil {
+		return nil
+	}
+
+	converted := map[string]*giteaConfigCriteriaWithExclusions{}
+	for name, c := range accounts {
+		if c == nil {
+			converted[name] = nil
+			continue
+		}
+		converted[name] = &giteaConfigCriteriaWithExclusions{
+			giteaConfigCriteria: giteaConfigCriteria{Names: c.Names},
+		}
+	}
+	return converted
+}
+
+//------------------------------------------------------------------------------
+
+func findGiteaRepos(
+	syncRoot, workDir string, cmdArgs []string, cfg *giteaConfig, listing listingOptions,
+	console io.Writer,
+) (idealRepoMap, rejectionReasonMap, error) {
+	token, err := cfg.Auth.token("gitea", cfg.Server, listing)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lister := giteaLister{gitea.ServerAndToken{
+		Server: cfg.Server,
+		Token:  token,
+	}}
+
+	return findAccountRepos(syncRoot, workDir, cmdArgs, cfg.asGitHub, lister, console)
+}
+
+func (opts *gitHubInitOptions) newGiteaConfig(server string, args []string, token string,
+) (*giteaConfig, error) {
+	lister := giteaLister{gitea.ServerAndToken{
+		Server: server,
+		Token:  token,
+	}}
+
+	gh, err := opts.newConfigFromAccounts(server, args, lister)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &giteaConfig{
+		Server:                 gh.Server,
+		SingleOrg:              gh.SingleOrg,
+		Orgs:                   newGiteaAccounts(gh.Orgs),
+		SingleUser:             gh.SingleUser,
+		Users:                  newGiteaAccounts(gh.Users),
+		SingleDirForAllRepos:   gh.SingleDirForAllRepos,
+		AccountPrefixSeparator: gh.AccountPrefixSeparator,
+	}
+	cfg.Names = gh.Names
+	return cfg, nil
+}
+
+//------------------------------------------------------------------------------
+
+// giteaLister adapts gitea.ServerAndToken to accountRepoLister.
+type giteaLister struct {
+	sat gitea.ServerAndToken
+}
+
+func (l giteaLister) GetAccount(ctx context.Context, name string) (github.Account, error) {
+	acct, err := l.sat.GetAccount(ctx, name)
+	if err != nil {
+		return github.Account{}, err
+	}
+
+	return github.Account{Login: acct.Login, Type: string(acct.Type)}, nil
+}
+
+func (l giteaLister) GetRepo(ctx context.Context, fullName string) (github.Repo, error) {
+	r, err := l.sat.GetRepo(ctx, fullName)
+	if err != nil {
+		return github.Repo{}, err
+	}
+
+	// The repo doesn't say whether its owner is an org or a user.
+	acct, err := l.GetAccount(ctx, r.Owner.Login)
+	if err != nil {
+		return github.Repo{}, err
+	}
+
+	return giteaToGitHubRepo(r, acct), nil
+}
+
+func (l giteaLister) ListRepos(ctx context.Context, progress io.Writer, account github.Account,
+	repoType github.RepoType,
+) ([]github.Repo, error) {
+	if repoType != github.AllRepos {
+		return nil, fmt.Errorf("gitea: unsupported repo type %q", repoType)
+	}
+
+	giteaAcct := gitea.Account{Login: account.Login, Type: gitea.AccountType(account.Type)}
+
+	repos, err := l.sat.ListRepos(ctx, progress, giteaAcct)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]github.Repo, 0, len(repos))
+	for _, r := range repos {
+		results = append(results, giteaToGitHubRepo(r, account))
+	}
+
+	return results, nil
+}
+
+func giteaToGitHubRepo(r gitea.Repo, account github.Account) github.Repo {
+	repo := github.Repo{
+		Name:     r.Name,
+		FullName: r.FullName,
+		Account:  github.Account{Login: r.Owner.Login, Type: account.Type},
+		CloneURL: r.CloneURL,
+		SSHURL:   r.SSHURL,
+
+		Archived:      r.Archived,
+		DefaultBranch: r.DefaultBranch,
+		Fork:          r.Fork,
+		IsTemplate:    r.Template,
+		Language:      r.Language,
+		Private:       r.Private || r.Internal,
+		Topics:        r.Topics,
+		Visibility:    giteaVisibility(r),
+
+		PushedAt:        r.UpdatedAt, // the closest thing Gitea has, see giteaConfigCriteria
+		Size:            r.Size,
+		StargazersCount: r.StarsCount,
+	}
+
+	if r.Parent != nil {
+		repo.Parent = &github.Repo{
+			Name:     r.Parent.Name,
+			FullName: r.Parent.FullName,
+			CloneURL: r.Parent.CloneURL,
+			SSHURL:   r.Parent.SSHURL,
+		}
+	}
+
+	return repo
+}
+
+func giteaVisibility(r gitea.Repo) string {
+	switch {
+	case r.Internal:
+		return "internal"
+	case r.Private:
+		return "private"
+	default:
+		return "public"
+	}
+}
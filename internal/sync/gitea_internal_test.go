@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bloomberg/go-testgroup"
+	"github.com/mikesep/frond/internal/gitea"
+)
+
+func Test_Gitea(t *testing.T) {
+	testgroup.RunInParallel(t, &giteaTests{})
+}
+
+type giteaTests struct{}
+
+func newFakeGiteaLister(t *testgroup.T) giteaLister {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/v1/orgs/tools/repos", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "1" {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		fmt.Fprint(w, `[
+			{"name": "cli", "full_name": "tools/cli", "owner": {"login": "tools"},
+			 "clone_url": "https://gitea.example.com/tools/cli.git",
+			 "default_branch": "main", "language": "Go", "topics": ["cli"]},
+			{"name": "old", "full_name": "tools/old", "owner": {"login": "tools"},
+			 "clone_url": "https://gitea.example.com/tools/old.git",
+			 "default_branch": "master", "archived": true}
+		]`)
+	})
+
+	ts := httptest.NewTLSServer(mux)
+	t.Cleanup(ts.Close)
+
+	return giteaLister{gitea.ServerAndToken{
+		Server: strings.TrimPrefix(ts.URL, "https://"),
+		Client: ts.Client(),
+	}}
+}
+
+func (*giteaTests) Repos_are_filtered_like_GitHub(t *testgroup.T) {
+	lister := newFakeGiteaLister(t)
+
+	cfg := &giteaConfig{
+		Server:    "gitea.example.com",
+		SingleOrg: "tools",
+	}
+	cfg.Archived = &falseVar
+	cfg.Exclude = &giteaConfigCriteria{Languages: []string{"rust"}}
+	t.Require.NoError(cfg.validate())
+
+	syncRoot := t.TempDir()
+	ideal, rejected, err := findAccountRepos(syncRoot, syncRoot, nil, cfg.asGitHub, lister,
+		ioutil.Discard)
+	t.Require.NoError(err)
+
+	t.Equal(idealRepoMap{
+		"gitea.example.com/tools/cli": {
+			Path:          "cli",
+			URL:           "https://gitea.example.com/tools/cli.git",
+			DefaultBranch: "main",
+		},
+	}, ideal)
+	t.Equal(rejectionReasonMap{
+		"gitea.example.com/tools/old": "repo is archived",
+	}, rejected)
+}
+
+func (*giteaTests) Config_only_has_what_Gitea_supports(t *testgroup.T) {
+	cfg, err := parseConfig(strings.NewReader(`
+gitea:
+  server: gitea.example.com
+  orgs:
+    tools:
+      names: [cli]
+      pushedWithin: 8w
+`))
+	t.Require.NoError(err)
+
+	gh := cfg.Gitea.asGitHub
+	t.Equal("gitea.example.com", gh.Server)
+	t.Equal([]string{"cli"}, gh.Orgs["tools"].Names)
+	t.Equal("8w", gh.Orgs["tools"].PushedWithin)
+	t.True(gh.Orgs["tools"].names.matches("cli"))
+
+	for _, field := range []string{"api: graphql", "teams: [owners]", "properties: {team: [x]}"} {
+		_, err := parseConfig(strings.NewReader(`
+gitea:
+  server: gitea.example.com
+  org: tools
+  ` + field + `
+`))
+		t.Error(err, field)
+	}
+}
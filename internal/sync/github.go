@@ -8,9 +8,11 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/mikesep/frond/internal/git"
 	"github.com/mikesep/frond/internal/github"
 )
 
@@ -29,6 +31,13 @@ type gitHubConfig struct {
 
 	SingleDirForAllRepos   *bool   `yaml:"singleDirForAllRepos,omitempty"`
 	AccountPrefixSeparator *string `yaml:"accountPrefixSeparator,omitempty"`
+
+	API  string      `yaml:"api,omitempty"` // how to list repos: rest (default) or graphql
+	Auth *authConfig `yaml:"auth,omitempty"`
+
+	CloneProtocol string `yaml:"cloneProtocol,omitempty"` // https (default) or ssh
+
+	token *gitHubToken // cached by cachedToken
 }
 
 type gitHubConfigCriteriaWithExclusions struct {
@@ -38,6 +47,7 @@ type gitHubConfigCriteriaWithExclusions struct {
 
 type gitHubConfigCriteria struct {
 	Names []string `yaml:"names,omitempty"`
+	Teams []string `yaml:"teams,omitempty"` // team slugs, for orgs only
 
 	Topics    []string `yaml:"topics,omitempty"`
 	Languages []string `yaml:"languages,omitempty"`
@@ -46,11 +56,111 @@ type gitHubConfigCriteria struct {
 	Fork       *bool `yaml:"fork,omitempty"`
 	IsTemplate *bool `yaml:"is_template,omitempty"`
 	Private    *bool `yaml:"private,omitempty"`
+
+	Visibility []string            `yaml:"visibility,omitempty"` // public, private, or internal
+	Properties map[string][]string `yaml:"properties,omitempty"` // custom property values
+
+	PushedWithin string `yaml:"pushedWithin,omitempty"` // like 365d, 8w, or 72h
+	MaxSizeMB    *int   `yaml:"maxSizeMB,omitempty"`
+	MinStars     *int   `yaml:"minStars,omitempty"`
+
+	// compiled from the patterns above by compileFilters
+	names, topics, languages, visibility filter
+	properties                           map[string]filter
+}
+
+func (c *gitHubConfigCriteriaWithExclusions) hasTeams() bool {
+	return c != nil && (len(c.Teams) > 0 || c.Exclude != nil && len(c.Exclude.Teams) > 0)
 }
 
 //------------------------------------------------------------------------------
 
-func (cfg gitHubConfig) validate() error {
+func (c *gitHubConfigCriteria) compileFilters() error {
+	var err error
+
+	if c.names, err = compileFieldFilter("names", c.Names, false); err != nil {
+		return err
+	}
+	if c.topics, err = compileFieldFilter("topics", c.Topics, false); err != nil {
+		return err
+	}
+	// languages are named like Go and TypeScript
+	if c.languages, err = compileFieldFilter("languages", c.Languages, true); err != nil {
+		return err
+	}
+	if c.visibility, err = compileFieldFilter("visibility", c.Visibility, false); err != nil {
+		return err
+	}
+
+	c.properties = make(map[string]filter, len(c.Properties))
+	for name, values := range c.Properties {
+		if c.properties[name], err = compileFieldFilter("properties."+name, values, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compileFilters compiles the filters of every set of criteria, which validate also does.
+func (cfg *gitHubConfig) compileFilters() error {
+	for _, c := range cfg.allCriteria() {
+		if err := c.compileFilters(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allCriteria returns every set of criteria in the config, including exclusions.
+func (cfg *gitHubConfig) allCriteria() []*gitHubConfigCriteria {
+	var all []*gitHubConfigCriteria
+
+	add := func(c *gitHubConfigCriteriaWithExclusions) {
+		if c == nil {
+			return
+		}
+		all = append(all, &c.gitHubConfigCriteria)
+		if c.Exclude != nil {
+			all = append(all, c.Exclude)
+		}
+	}
+
+	add(&cfg.gitHubConfigCriteriaWithExclusions)
+	for _, c := range cfg.Orgs {
+		add(c)
+	}
+	for _, c := range cfg.Users {
+		add(c)
+	}
+
+	return all
+}
+
+// usesProperties says whether any criteria mention custom properties.
+func (cfg gitHubConfig) usesProperties() bool {
+	for _, c := range cfg.allCriteria() {
+		if len(c.Properties) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// usesTeams says whether any criteria mention teams.
+func (cfg gitHubConfig) usesTeams() bool {
+	if cfg.hasTeams() {
+		return true
+	}
+	for _, criteria := range cfg.Orgs {
+		if criteria.hasTeams() {
+			return true
+		}
+	}
+	return false // validate rejects teams for users
+}
+
+func (cfg *gitHubConfig) validate() error {
 	if cfg.Server == "" {
 		return fmt.Errorf("server is missing")
 	}
@@ -77,6 +187,66 @@ func (cfg gitHubConfig) validate() error {
 		}
 	}
 
+	if cfg.hasTeams() && (cfg.SingleUser != "" || len(cfg.Users) != 0) {
+		return fmt.Errorf("teams only work with orgs, not users")
+	}
+	for name, criteria := range cfg.Users {
+		if criteria.hasTeams() {
+			return fmt.Errorf("users.%s: teams only work with orgs", name)
+		}
+	}
+
+	for _, c := range cfg.allCriteria() {
+		for _, v := range c.Visibility {
+			switch v {
+			case "public", "private", "internal":
+			default:
+				return fmt.Errorf("unknown visibility %q (expected public, private, or internal)", v)
+			}
+		}
+
+		if c.PushedWithin != "" {
+			if _, err := parseAge(c.PushedWithin); err != nil {
+				return fmt.Errorf("pushedWithin: %w", err)
+			}
+		}
+
+		if err := c.compileFilters(); err != nil {
+			return err
+		}
+	}
+
+	excludes := []*gitHubConfigCriteria{cfg.Exclude}
+	for _, c := range cfg.Orgs {
+		if c != nil {
+			excludes = append(excludes, c.Exclude)
+		}
+	}
+	for _, c := range cfg.Users {
+		if c != nil {
+			excludes = append(excludes, c.Exclude)
+		}
+	}
+	for _, e := range excludes {
+		if e != nil && (e.PushedWithin != "" || e.MaxSizeMB != nil || e.MinStars != nil) {
+			return fmt.Errorf("exclude can't have pushedWithin, maxSizeMB, or minStars")
+		}
+	}
+
+	if err := validateCloneProtocol(cfg.CloneProtocol); err != nil {
+		return err
+	}
+
+	switch cfg.API {
+	case "", "rest":
+	case "graphql":
+		if cfg.usesProperties() {
+			return fmt.Errorf("properties need the rest api")
+		}
+	default:
+		return fmt.Errorf("unknown api %q (expected rest or graphql)", cfg.API)
+	}
+
 	return nil
 }
 
@@ -177,7 +347,8 @@ func (cfg gitHubConfig) pathForRepo(account, repo string) string {
 	return filepath.Join(account, repoDir)
 }
 
-func (cfg gitHubConfig) filterRepo(repo github.Repo) (rejectionReason string, err error) {
+func (cfg gitHubConfig) filterRepo(repo github.Repo, teams teamRepos,
+) (rejectionReason string, err error) {
 	var accountCriteria *gitHubConfigCriteriaWithExclusions
 	switch repo.Account.Type {
 	case "Organization":
@@ -188,40 +359,36 @@ func (cfg gitHubConfig) filterRepo(repo github.Repo) (rejectionReason string, er
 		panic(fmt.Sprintf("unexpected repo.Account.Type! repo = %#v", repo))
 	}
 
-	names := cfg.Names
+	names, namesFilter := cfg.Names, cfg.names
 	if accountCriteria != nil && len(accountCriteria.Names) > 0 {
-		names = accountCriteria.Names
+		names, namesFilter = accountCriteria.Names, accountCriteria.names
 	}
-	matched, err := matchesAnyFilter(repo.Name, names)
-	if err != nil {
-		return "", err
-	}
-	if !matched {
+	if !namesFilter.matches(repo.Name) {
 		return fmt.Sprintf("%s doesn't match any name in %v", repo.Name, names), nil
 	}
 
-	topics := cfg.Topics
-	if accountCriteria != nil && len(accountCriteria.Topics) > 0 {
-		topics = accountCriteria.Topics
+	if repo.Account.Type == "Organization" {
+		included, _ := cfg.teamsForOrg(repo.Account.Login)
+
+		if len(included) > 0 && !teams.anyContains(repo, included) {
+			return fmt.Sprintf("repo isn't in any team in %v", included), nil
+		}
 	}
-	matched, err = anyWordMatchesAnyFilter(repo.Topics, topics)
-	if err != nil {
-		return "", err
+
+	topics, topicsFilter := cfg.Topics, cfg.topics
+	if accountCriteria != nil && len(accountCriteria.Topics) > 0 {
+		topics, topicsFilter = accountCriteria.Topics, accountCriteria.topics
 	}
-	if !matched {
+	if !topicsFilter.matchesAny(repo.Topics) {
 		return fmt.Sprintf("none of the repo topics %v match any config topic %v",
 			repo.Topics, topics), nil
 	}
 
-	languages := cfg.Languages
+	languages, languagesFilter := cfg.Languages, cfg.languages
 	if accountCriteria != nil && len(accountCriteria.Languages) > 0 {
-		languages = accountCriteria.Languages
-	}
-	matched, err = matchesAnyFilter(repo.Language, languages)
-	if err != nil {
-		return "", err
+		languages, languagesFilter = accountCriteria.Languages, accountCriteria.languages
 	}
-	if !matched {
+	if !languagesFilter.matches(repo.Language) {
 		return fmt.Sprintf("%s doesn't match any language in %v", repo.Language, languages), nil
 	}
 
@@ -273,7 +440,216 @@ func (cfg gitHubConfig) filterRepo(repo github.Repo) (rejectionReason string, er
 		return fmt.Sprintf("repo %s private", isOrIsNot), nil
 	}
 
-	return "", nil
+	visibility, visibilityFilter := cfg.Visibility, cfg.visibility
+	if accountCriteria != nil && len(accountCriteria.Visibility) > 0 {
+		visibility, visibilityFilter = accountCriteria.Visibility, accountCriteria.visibility
+	}
+	if !visibilityFilter.matches(repo.VisibilityOrPrivate()) {
+		return fmt.Sprintf("visibility %s isn't any of %v",
+			repo.VisibilityOrPrivate(), visibility), nil
+	}
+
+	properties, propertiesFilters := cfg.Properties, cfg.properties
+	if accountCriteria != nil && len(accountCriteria.Properties) > 0 {
+		properties, propertiesFilters = accountCriteria.Properties, accountCriteria.properties
+	}
+	for _, name := range sortedKeys(properties) {
+		values := repo.CustomProperties[name]
+		if !propertiesFilters[name].matchesAny(values) {
+			return fmt.Sprintf("property %s %v doesn't match any of %v",
+				name, []string(values), properties[name]), nil
+		}
+	}
+
+	pushedWithin := cfg.PushedWithin
+	if accountCriteria != nil && accountCriteria.PushedWithin != "" {
+		pushedWithin = accountCriteria.PushedWithin
+	}
+	if pushedWithin != "" {
+		age, err := parseAge(pushedWithin)
+		if err != nil {
+			return "", err
+		}
+		if repo.PushedAt.IsZero() {
+			return "repo has never been pushed to", nil
+		}
+		if since := time.Since(repo.PushedAt); since > age {
+			return fmt.Sprintf("repo was last pushed %d days ago, not within %s",
+				int(since.Hours()/24), pushedWithin), nil
+		}
+	}
+
+	maxSizeMB := cfg.MaxSizeMB
+	if accountCriteria != nil && accountCriteria.MaxSizeMB != nil {
+		maxSizeMB = accountCriteria.MaxSizeMB
+	}
+	if maxSizeMB != nil && repo.Size > *maxSizeMB*1024 {
+		return fmt.Sprintf("repo is %d MB, more than %d MB", repo.Size/1024, *maxSizeMB), nil
+	}
+
+	minStars := cfg.MinStars
+	if accountCriteria != nil && accountCriteria.MinStars != nil {
+		minStars = accountCriteria.MinStars
+	}
+	if minStars != nil && repo.StargazersCount < *minStars {
+		return fmt.Sprintf("repo has %d stars, fewer than %d", repo.StargazersCount, *minStars), nil
+	}
+
+	return cfg.exclusionReason(repo, cfg.exclusionsFor(accountCriteria), teams), nil
+}
+
+// exclusionsFor combines the top-level exclusions with an account's. Like the other criteria,
+// each of the account's exclusions replaces the top-level one, so an account can loosen them.
+func (cfg gitHubConfig) exclusionsFor(accountCriteria *gitHubConfigCriteriaWithExclusions,
+) gitHubConfigCriteria {
+	var exclude gitHubConfigCriteria
+	if cfg.Exclude != nil {
+		exclude = *cfg.Exclude
+	}
+
+	if accountCriteria == nil || accountCriteria.Exclude == nil {
+		return exclude
+	}
+	acct := accountCriteria.Exclude
+
+	if len(acct.Names) > 0 {
+		exclude.Names, exclude.names = acct.Names, acct.names
+	}
+	if len(acct.Teams) > 0 {
+		exclude.Teams = acct.Teams
+	}
+	if len(acct.Topics) > 0 {
+		exclude.Topics, exclude.topics = acct.Topics, acct.topics
+	}
+	if len(acct.Languages) > 0 {
+		exclude.Languages, exclude.languages = acct.Languages, acct.languages
+	}
+	if acct.Archived != nil {
+		exclude.Archived = acct.Archived
+	}
+	if acct.Fork != nil {
+		exclude.Fork = acct.Fork
+	}
+	if acct.IsTemplate != nil {
+		exclude.IsTemplate = acct.IsTemplate
+	}
+	if acct.Private != nil {
+		exclude.Private = acct.Private
+	}
+	if len(acct.Visibility) > 0 {
+		exclude.Visibility, exclude.visibility = acct.Visibility, acct.visibility
+	}
+	if len(acct.Properties) > 0 {
+		exclude.Properties, exclude.properties = acct.Properties, acct.properties
+	}
+
+	return exclude
+}
+
+// exclusionReason says which of the exclusions matches the repo, if any does.
+func (cfg gitHubConfig) exclusionReason(repo github.Repo, exclude gitHubConfigCriteria,
+	teams teamRepos,
+) string {
+	if len(exclude.Names) > 0 && exclude.names.matches(repo.Name) {
+		return "excluded by name " + repo.Name
+	}
+
+	for _, team := range exclude.Teams {
+		if repo.Account.Type == "Organization" && teams.anyContains(repo, []string{team}) {
+			return "excluded by team " + team
+		}
+	}
+
+	for _, topic := range repo.Topics {
+		if len(exclude.Topics) == 0 {
+			break
+		}
+		if exclude.topics.matches(topic) {
+			return "excluded by topic " + topic
+		}
+	}
+
+	if len(exclude.Languages) > 0 && exclude.languages.matches(repo.Language) {
+		return "excluded by language " + repo.Language
+	}
+
+	for _, b := range []struct {
+		exclude *bool
+		is      bool
+		what    string
+	}{
+		{exclude.Archived, repo.Archived, "archived"},
+		{exclude.Fork, repo.Fork, "a fork"},
+		{exclude.IsTemplate, repo.IsTemplate, "a template"},
+		{exclude.Private, repo.Private, "private"},
+	} {
+		if b.exclude == nil || b.is != *b.exclude {
+			continue
+		}
+		if b.is {
+			return fmt.Sprintf("excluded because repo is %s", b.what)
+		}
+		return fmt.Sprintf("excluded because repo is not %s", b.what)
+	}
+
+	if len(exclude.Visibility) > 0 && exclude.visibility.matches(repo.VisibilityOrPrivate()) {
+		return "excluded by visibility " + repo.VisibilityOrPrivate()
+	}
+
+	for _, name := range sortedKeys(exclude.Properties) {
+		if len(exclude.Properties[name]) == 0 {
+			continue
+		}
+		for _, value := range repo.CustomProperties[name] {
+			if exclude.properties[name].matches(value) {
+				return fmt.Sprintf("excluded by property %s %s", name, value)
+			}
+		}
+	}
+
+	return ""
+}
+
+// parseAge parses durations in days (365d) and weeks (8w), as well as the ones that
+// time.ParseDuration understands.
+func parseAge(s string) (time.Duration, error) {
+	const day = 24 * time.Hour
+	units := map[byte]time.Duration{'d': day, 'w': 7 * day}
+
+	if len(s) > 1 {
+		if unit, ok := units[s[len(s)-1]]; ok {
+			if n, err := strconv.Atoi(s[:len(s)-1]); err == nil && n >= 0 {
+				return time.Duration(n) * unit, nil
+			}
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("bad duration %q (expected something like 365d)", s)
+	}
+	return d, nil
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// teamsForOrg returns the teams that an org's repos have to be in, and the teams they can't be in.
+func (cfg gitHubConfig) teamsForOrg(org string) (included, excluded []string) {
+	accountCriteria := cfg.Orgs[org]
+
+	included = cfg.Teams
+	if accountCriteria != nil && len(accountCriteria.Teams) > 0 {
+		included = accountCriteria.Teams
+	}
+
+	return included, cfg.exclusionsFor(accountCriteria).Teams
 }
 
 func (cfg gitHubConfig) orgOrUser(name string) (org, user string) {
@@ -290,24 +666,119 @@ func (cfg gitHubConfig) orgOrUser(name string) (org, user string) {
 
 //------------------------------------------------------------------------------
 
+// accountRepoLister is implemented by github.ServerAndToken, and by adapters for other servers
+// with GitHub-like orgs and users.
+type accountRepoLister interface {
+	GetAccount(ctx context.Context, name string) (github.Account, error)
+	GetRepo(ctx context.Context, fullName string) (github.Repo, error)
+	ListRepos(ctx context.Context, progress io.Writer, account github.Account,
+		repoType github.RepoType) ([]github.Repo, error)
+}
+
+// teamRepoLister is implemented by github.ServerAndToken, but not by every accountRepoLister.
+type teamRepoLister interface {
+	ListTeamRepos(ctx context.Context, progress io.Writer, org, team string) ([]github.Repo, error)
+}
+
+// teamRepos holds the lowercased full names of each team's repos, keyed by org/team.
+type teamRepos map[string]map[string]bool
+
+func (tr teamRepos) anyContains(repo github.Repo, teams []string) bool {
+	for _, team := range teams {
+		if tr[repo.Account.Login+"/"+team][strings.ToLower(repo.FullName)] {
+			return true
+		}
+	}
+	return false
+}
+
 func findGitHubRepos(
-	syncRoot, workDir string, cmdArgs []string, cfg *gitHubConfig, console io.Writer,
+	syncRoot, workDir string, cmdArgs []string, cfg *gitHubConfig, listing listingOptions,
+	console io.Writer,
 ) (idealRepoMap, rejectionReasonMap, error) {
-	ctx := context.Background()
+	// Offline listings come from the cache, so they don't need a token.
+	var token string
+	var gitCredential *github.GitCredential
+	if !listing.offline {
+		var err error
+		token, gitCredential, err = cfg.cachedToken(listing)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 
-	orgs, users, individualRepos, err := cmdlineToGitHubOrgsUsersRepos(syncRoot, workDir, cmdArgs, cfg)
-	if err != nil {
-		return nil, nil, err
+	var rateLimit github.RateLimit
+
+	lister := gitHubLister{
+		ServerAndToken: github.ServerAndToken{
+			Server:        cfg.Server,
+			Token:         token,
+			Console:       console,
+			RateLimit:     &rateLimit,
+			Cache:         listing.gitHubCache(cfg.Server, cfg.Auth),
+			GitCredential: gitCredential,
+		},
+		useGraphQL: cfg.API == "graphql" && !listing.offline, // GraphQL isn't cached
+	}
+
+	idealRepos, rejectedRepos, err := findAccountRepos(
+		syncRoot, workDir, cmdArgs, cfg, lister, console)
+
+	if rateLimit.Limit > 0 {
+		fmt.Fprintf(console, "%s has %s.\n", cfg.Server, rateLimit)
 	}
 
-	cred, err := git.FillCredential("https", cfg.Server)
+	return idealRepos, rejectedRepos, err
+}
+
+type gitHubToken struct {
+	token         string
+	gitCredential *github.GitCredential
+	err           error
+}
+
+// cachedToken gets the token the first time it's needed, and reuses it after that, so that
+// listing repos and resolving moved ones only go through the credential helpers once.
+func (cfg *gitHubConfig) cachedToken(listing listingOptions,
+) (string, *github.GitCredential, error) {
+	if cfg.token == nil {
+		token, gitCredential, err := cfg.Auth.gitHubToken(cfg.Server, listing)
+		cfg.token = &gitHubToken{token: token, gitCredential: gitCredential, err: err}
+	}
+	return cfg.token.token, cfg.token.gitCredential, cfg.token.err
+}
+
+// gitHubLister lists repos with GraphQL when the config asks for it, and falls back to REST
+// when that fails.
+type gitHubLister struct {
+	github.ServerAndToken
+	useGraphQL bool
+}
+
+func (l gitHubLister) ListRepos(ctx context.Context, progress io.Writer, account github.Account,
+	repoType github.RepoType,
+) ([]github.Repo, error) {
+	if l.useGraphQL {
+		repos, err := l.ListReposGraphQL(ctx, progress, account, repoType)
+		if err == nil {
+			return repos, nil
+		}
+		fmt.Fprintf(progress, " GraphQL failed (%v), falling back to REST..", err)
+	}
+
+	return l.ServerAndToken.ListRepos(ctx, progress, account, repoType)
+}
+
+func findAccountRepos(
+	syncRoot, workDir string, cmdArgs []string, cfg *gitHubConfig, ghSAT accountRepoLister,
+	console io.Writer,
+) (idealRepoMap, rejectionReasonMap, error) {
+	ctx := context.Background()
+
+	orgs, users, individualRepos, err := cmdlineToGitHubOrgsUsersRepos(syncRoot, workDir, cmdArgs, cfg)
 	if err != nil {
 		return nil, nil, err
 	}
-	ghSAT := github.ServerAndToken{
-		Server: cfg.Server,
-		Token:  cred.Password,
-	}
 
 	var unfilteredRepos []github.Repo
 
@@ -357,6 +828,16 @@ func findGitHubRepos(
 		fmt.Fprintf(console, " checked %d.\n", len(individualRepos))
 	}
 
+	teams, err := findTeamRepos(ctx, cfg, ghSAT, unfilteredRepos, console)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	protocol, err := preferredCloneProtocol(cfg.CloneProtocol)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// filter the repos
 
 	idealRepos := idealRepoMap{}
@@ -368,7 +849,7 @@ func findGitHubRepos(
 			return nil, nil, err
 		}
 
-		reason, err := cfg.filterRepo(r)
+		reason, err := cfg.filterRepo(r, teams)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -383,11 +864,27 @@ func findGitHubRepos(
 			return nil, nil, err
 		}
 
-		idealRepos[compURL] = idealRepo{
+		ideal := idealRepo{
 			Path:          pathToRepo,
-			URL:           r.CloneURL,
+			URL:           pickCloneURL(protocol, r.CloneURL, r.SSHURL),
 			DefaultBranch: r.DefaultBranch,
 		}
+
+		switch {
+		case r.Parent != nil: // GraphQL listings include it
+			ideal.UpstreamURL = pickCloneURL(protocol, r.Parent.CloneURL, r.Parent.SSHURL)
+		case r.Fork:
+			r := r
+			ideal.findUpstreamURL = func() (string, error) {
+				parent, err := findForkParent(ctx, ghSAT, r)
+				if err != nil || parent == nil {
+					return "", err
+				}
+				return pickCloneURL(protocol, parent.CloneURL, parent.SSHURL), nil
+			}
+		}
+
+		idealRepos[compURL] = ideal
 	}
 
 	fmt.Fprintf(console, "Filtered out %d and kept %d.\n", len(rejectedRepos), len(idealRepos))
@@ -395,6 +892,50 @@ func findGitHubRepos(
 	return idealRepos, rejectedRepos, nil
 }
 
+// findTeamRepos lists the repos of the teams that the config mentions for the repos' orgs.
+func findTeamRepos(ctx context.Context, cfg *gitHubConfig, ghSAT accountRepoLister,
+	repos []github.Repo, console io.Writer,
+) (teamRepos, error) {
+	teams := teamRepos{}
+
+	for _, r := range repos {
+		if r.Account.Type != "Organization" {
+			continue
+		}
+
+		org := r.Account.Login
+		included, excluded := cfg.teamsForOrg(org)
+
+		for _, team := range append(append([]string{}, included...), excluded...) {
+			key := org + "/" + team
+			if _, ok := teams[key]; ok {
+				continue
+			}
+
+			lister, ok := ghSAT.(teamRepoLister)
+			if !ok {
+				return nil, fmt.Errorf("%s doesn't support teams", cfg.Server)
+			}
+
+			fmt.Fprintf(console, "Finding repositories in team %s/%s..", cfg.Server, key)
+			rr, err := lister.ListTeamRepos(ctx, console, org, team)
+			if err != nil {
+				fmt.Fprintf(console, " FAILED!\n")
+				return nil, err
+			}
+			fmt.Fprintf(console, " found %d.\n", len(rr))
+
+			names := make(map[string]bool, len(rr))
+			for _, tr := range rr {
+				names[strings.ToLower(tr.FullName)] = true
+			}
+			teams[key] = names
+		}
+	}
+
+	return teams, nil
+}
+
 func cmdlineToGitHubOrgsUsersRepos(syncRoot, workDir string, cmdArgs []string, cfg *gitHubConfig,
 ) (orgs, users, repos []string, err error) {
 	if !filepath.IsAbs(syncRoot) {
@@ -7,8 +7,10 @@ import (
 	"path/filepath"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/bloomberg/go-testgroup"
+	"github.com/mikesep/frond/internal/github"
 )
 
 func Test_GitHub_paths(t *testing.T) {
@@ -309,3 +311,266 @@ func (grp *singleAccountTests) PanicsWhenDirAndPrefixConflict(t *testgroup.T) {
 		cfg.pathToOrgUserRepo(filepath.Join(acct, "different"+arrow+"repo"))
 	})
 }
+
+//------------------------------------------------------------------------------
+
+func Test_GitHub_filter(t *testing.T) {
+	testgroup.RunInParallel(t, &gitHubFilterTests{})
+}
+
+type gitHubFilterTests struct{}
+
+type gitHubFilterTestcase struct {
+	name   string
+	repo   github.Repo
+	reason string
+}
+
+func newGitHubOrgRepo(name string) github.Repo {
+	return github.Repo{
+		Name:     name,
+		FullName: "bloomberg/" + name,
+		Account:  github.Account{Login: "bloomberg", Type: "Organization"},
+	}
+}
+
+func runGitHubFilterTestcases(t *testgroup.T, cfg gitHubConfig, testcases []gitHubFilterTestcase) {
+	t.Require.NoError(cfg.compileFilters())
+
+	for _, tc := range testcases {
+		reason, err := cfg.filterRepo(tc.repo, nil)
+		t.NoError(err, tc.name)
+		t.Equal(tc.reason, reason, tc.name)
+	}
+}
+
+func (*gitHubFilterTests) Visibility(t *testgroup.T) {
+	cfg := gitHubConfig{SingleOrg: "bloomberg"}
+	cfg.Visibility = []string{"internal", "private"}
+
+	internal := newGitHubOrgRepo("internal")
+	internal.Private = true
+	internal.Visibility = "internal"
+
+	private := newGitHubOrgRepo("private") // from a server that doesn't report visibility
+	private.Private = true
+
+	runGitHubFilterTestcases(t, cfg, []gitHubFilterTestcase{
+		{name: "internal", repo: internal},
+		{name: "private", repo: private},
+		{
+			name:   "public",
+			repo:   newGitHubOrgRepo("public"),
+			reason: "visibility public isn't any of [internal private]",
+		},
+	})
+}
+
+func (*gitHubFilterTests) Properties(t *testgroup.T) {
+	cfg := gitHubConfig{
+		Orgs: map[string]*gitHubConfigCriteriaWithExclusions{
+			"bloomberg": {gitHubConfigCriteria: gitHubConfigCriteria{
+				Properties: map[string][]string{"team": {"tools", "infra-*"}, "env": {"prod"}},
+			}},
+		},
+	}
+
+	repo := func(props map[string]github.PropertyValue) github.Repo {
+		r := newGitHubOrgRepo("repo")
+		r.CustomProperties = props
+		return r
+	}
+
+	runGitHubFilterTestcases(t, cfg, []gitHubFilterTestcase{
+		{
+			name: "all match",
+			repo: repo(map[string]github.PropertyValue{
+				"team": {"infra-core"},
+				"env":  {"staging", "prod"},
+			}),
+		},
+		{
+			name:   "one doesn't match",
+			repo:   repo(map[string]github.PropertyValue{"team": {"docs"}, "env": {"prod"}}),
+			reason: "property team [docs] doesn't match any of [tools infra-*]",
+		},
+		{
+			name:   "unset",
+			repo:   repo(map[string]github.PropertyValue{"team": {"tools"}}),
+			reason: "property env [] doesn't match any of [prod]",
+		},
+	})
+}
+
+func (*gitHubFilterTests) Activity(t *testgroup.T) {
+	maxSizeMB, minStars := 100, 5
+
+	cfg := gitHubConfig{SingleOrg: "bloomberg"}
+	cfg.PushedWithin = "365d"
+	cfg.MaxSizeMB = &maxSizeMB
+	cfg.MinStars = &minStars
+
+	repo := func(pushedDaysAgo, sizeKB, stars int) github.Repo {
+		r := newGitHubOrgRepo("repo")
+		r.PushedAt = time.Now().Add(-time.Duration(pushedDaysAgo) * 24 * time.Hour)
+		r.Size = sizeKB
+		r.StargazersCount = stars
+		return r
+	}
+
+	never := repo(0, 0, 10)
+	never.PushedAt = time.Time{}
+
+	runGitHubFilterTestcases(t, cfg, []gitHubFilterTestcase{
+		{name: "active", repo: repo(30, 1024, 10)},
+		{
+			name:   "stale",
+			repo:   repo(1000, 1024, 10),
+			reason: "repo was last pushed 1000 days ago, not within 365d",
+		},
+		{name: "never pushed", repo: never, reason: "repo has never been pushed to"},
+		{
+			name:   "big",
+			repo:   repo(30, 200*1024, 10),
+			reason: "repo is 200 MB, more than 100 MB",
+		},
+		{
+			name:   "unpopular",
+			repo:   repo(30, 1024, 2),
+			reason: "repo has 2 stars, fewer than 5",
+		},
+	})
+}
+
+func (*gitHubFilterTests) Parse_ages(t *testgroup.T) {
+	for s, want := range map[string]time.Duration{
+		"365d": 365 * 24 * time.Hour,
+		"8w":   8 * 7 * 24 * time.Hour,
+		"72h":  72 * time.Hour,
+		"0d":   0,
+	} {
+		got, err := parseAge(s)
+		t.NoError(err, s)
+		t.Equal(want, got, s)
+	}
+
+	for _, s := range []string{"", "d", "-1d", "1y", "soon"} {
+		_, err := parseAge(s)
+		t.Error(err, s)
+	}
+}
+
+func (*gitHubFilterTests) Exclusions(t *testgroup.T) {
+	trueVar := true
+
+	cfg := gitHubConfig{
+		Orgs: map[string]*gitHubConfigCriteriaWithExclusions{
+			"bloomberg": nil,
+			"apache": {Exclude: &gitHubConfigCriteria{
+				Topics:   []string{"incubator"},
+				Archived: &falseVar, // only keep archived repos
+			}},
+		},
+	}
+	cfg.Exclude = &gitHubConfigCriteria{
+		Names:      []string{"*-old"},
+		Topics:     []string{"deprecated"},
+		Languages:  []string{"Perl"},
+		Archived:   &trueVar,
+		Fork:       &trueVar,
+		IsTemplate: &trueVar,
+		Private:    &trueVar,
+		Visibility: []string{"internal"},
+		Properties: map[string][]string{"lifecycle": {"sunset"}},
+	}
+
+	repo := func(account, name string, change func(r *github.Repo)) github.Repo {
+		r := newGitHubOrgRepo(name)
+		r.Account.Login = account
+		r.FullName = account + "/" + name
+		if change != nil {
+			change(&r)
+		}
+		return r
+	}
+
+	runGitHubFilterTestcases(t, cfg, []gitHubFilterTestcase{
+		{name: "kept", repo: repo("bloomberg", "cli", nil)},
+		{
+			name:   "name",
+			repo:   repo("bloomberg", "cli-old", nil),
+			reason: "excluded by name cli-old",
+		},
+		{
+			name: "topic",
+			repo: repo("bloomberg", "cli", func(r *github.Repo) {
+				r.Topics = []string{"go", "deprecated"}
+			}),
+			reason: "excluded by topic deprecated",
+		},
+		{
+			name:   "language",
+			repo:   repo("bloomberg", "cli", func(r *github.Repo) { r.Language = "Perl" }),
+			reason: "excluded by language Perl",
+		},
+		{
+			name:   "archived",
+			repo:   repo("bloomberg", "cli", func(r *github.Repo) { r.Archived = true }),
+			reason: "excluded because repo is archived",
+		},
+		{
+			name:   "fork",
+			repo:   repo("bloomberg", "cli", func(r *github.Repo) { r.Fork = true }),
+			reason: "excluded because repo is a fork",
+		},
+		{
+			name:   "template",
+			repo:   repo("bloomberg", "cli", func(r *github.Repo) { r.IsTemplate = true }),
+			reason: "excluded because repo is a template",
+		},
+		{
+			name:   "private",
+			repo:   repo("bloomberg", "cli", func(r *github.Repo) { r.Private = true }),
+			reason: "excluded because repo is private",
+		},
+		{
+			name:   "visibility",
+			repo:   repo("bloomberg", "cli", func(r *github.Repo) { r.Visibility = "internal" }),
+			reason: "excluded by visibility internal",
+		},
+		{
+			name: "property",
+			repo: repo("bloomberg", "cli", func(r *github.Repo) {
+				r.CustomProperties = map[string]github.PropertyValue{"lifecycle": {"sunset"}}
+			}),
+			reason: "excluded by property lifecycle sunset",
+		},
+
+		// apache's exclusions replace the top-level ones they overlap with
+		{
+			name: "account topic replaces top-level topic",
+			repo: repo("apache", "kafka", func(r *github.Repo) {
+				r.Archived = true
+				r.Topics = []string{"deprecated"}
+			}),
+		},
+		{
+			name: "account topic",
+			repo: repo("apache", "kafka", func(r *github.Repo) {
+				r.Archived = true
+				r.Topics = []string{"incubator"}
+			}),
+			reason: "excluded by topic incubator",
+		},
+		{
+			name:   "account boolean",
+			repo:   repo("apache", "kafka", nil),
+			reason: "excluded because repo is not archived",
+		},
+		{
+			name:   "top-level exclusions still apply",
+			repo:   repo("apache", "kafka-old", func(r *github.Repo) { r.Archived = true }),
+			reason: "excluded by name kafka-old",
+		},
+	})
+}
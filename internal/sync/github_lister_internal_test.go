@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bloomberg/go-testgroup"
+	"github.com/mikesep/frond/internal/github"
+)
+
+func Test_GitHub_lister(t *testing.T) {
+	testgroup.RunInParallel(t, &gitHubListerTests{})
+}
+
+type gitHubListerTests struct{}
+
+// newFakeGHES serves the REST listing, and fails every GraphQL query.
+func newFakeGHES(t *testgroup.T) github.ServerAndToken {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/graphql", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest) // not retried
+	})
+	mux.HandleFunc("/api/v3/orgs/bloomberg/repos", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"name": "go-testgroup", "full_name": "bloomberg/go-testgroup",
+			"owner": {"login": "bloomberg", "type": "Organization"}}]`)
+	})
+
+	ts := httptest.NewTLSServer(mux)
+	t.Cleanup(ts.Close)
+
+	return github.ServerAndToken{
+		Server: strings.TrimPrefix(ts.URL, "https://"),
+		Client: ts.Client(),
+	}
+}
+
+func (*gitHubListerTests) GraphQL_falls_back_to_REST(t *testgroup.T) {
+	lister := gitHubLister{ServerAndToken: newFakeGHES(t), useGraphQL: true}
+
+	var progress bytes.Buffer
+	repos, err := lister.ListRepos(context.Background(), &progress,
+		github.Account{Login: "bloomberg", Type: "Organization"}, github.AllRepos)
+	t.Require.NoError(err)
+
+	t.Require.Len(repos, 1)
+	t.Equal("bloomberg/go-testgroup", repos[0].FullName)
+	t.Contains(progress.String(), "falling back to REST")
+}
+
+func (*gitHubListerTests) REST_by_default(t *testgroup.T) {
+	lister := gitHubLister{ServerAndToken: newFakeGHES(t)}
+
+	var progress bytes.Buffer
+	repos, err := lister.ListRepos(context.Background(), &progress,
+		github.Account{Login: "bloomberg", Type: "Organization"}, github.AllRepos)
+	t.Require.NoError(err)
+
+	t.Len(repos, 1)
+	t.NotContains(progress.String(), "GraphQL")
+}
+
+func (*gitHubListerTests) Reject_unknown_api(t *testgroup.T) {
+	_, err := parseConfig(strings.NewReader(`
+github:
+  server: github.com
+  org: bloomberg
+  api: soap
+`))
+	t.Error(err)
+
+	cfg, err := parseConfig(strings.NewReader(`
+github:
+  server: github.com
+  org: bloomberg
+  api: graphql
+`))
+	t.Require.NoError(err)
+	t.Equal("graphql", cfg.GitHub.API)
+}
@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/bloomberg/go-testgroup"
+	"github.com/mikesep/frond/internal/github"
+)
+
+func Test_GitHub_teams(t *testing.T) {
+	testgroup.RunInParallel(t, &gitHubTeamsTests{})
+}
+
+type gitHubTeamsTests struct{}
+
+// newFakeGHESWithTeams has an org with three repos. The tools team has two of them, and the
+// legacy team has one of those.
+func newFakeGHESWithTeams(t *testgroup.T) gitHubLister {
+	repo := func(name string) string {
+		return fmt.Sprintf(`{"name": %[1]q, "full_name": "bloomberg/%[1]s",
+			"clone_url": "https://ghes.example.com/bloomberg/%[1]s.git",
+			"owner": {"login": "bloomberg", "type": "Organization"}}`, name)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/orgs/bloomberg/repos", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "[%s, %s, %s]", repo("cli"), repo("api"), repo("docs"))
+	})
+	mux.HandleFunc("/api/v3/orgs/bloomberg/teams/tools/repos", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "[%s, %s]", repo("cli"), repo("api"))
+	})
+	mux.HandleFunc("/api/v3/orgs/bloomberg/teams/legacy/repos", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "[%s]", repo("api"))
+	})
+
+	ts := httptest.NewTLSServer(mux)
+	t.Cleanup(ts.Close)
+
+	return gitHubLister{ServerAndToken: github.ServerAndToken{
+		Server: strings.TrimPrefix(ts.URL, "https://"),
+		Client: ts.Client(),
+	}}
+}
+
+func findTeamFilteredRepos(t *testgroup.T, cfg *gitHubConfig) (kept, rejected []string) {
+	syncRoot := t.TempDir()
+	ideal, rejections, err := findAccountRepos(syncRoot, syncRoot, nil, cfg,
+		newFakeGHESWithTeams(t), ioutil.Discard)
+	t.Require.NoError(err)
+
+	for _, r := range ideal {
+		kept = append(kept, r.Path)
+	}
+	for _, reason := range rejections {
+		rejected = append(rejected, reason)
+	}
+	sort.Strings(kept)
+	sort.Strings(rejected)
+	return kept, rejected
+}
+
+func (*gitHubTeamsTests) Teams_limit_repos(t *testgroup.T) {
+	cfg := &gitHubConfig{Server: "ghes.example.com", SingleOrg: "bloomberg"}
+	cfg.Teams = []string{"tools"}
+
+	kept, rejected := findTeamFilteredRepos(t, cfg)
+	t.Equal([]string{"api", "cli"}, kept)
+	t.Equal([]string{"repo isn't in any team in [tools]"}, rejected)
+}
+
+func (*gitHubTeamsTests) Excluded_teams_remove_repos(t *testgroup.T) {
+	cfg := &gitHubConfig{Server: "ghes.example.com", SingleOrg: "bloomberg"}
+	cfg.Teams = []string{"tools"}
+	cfg.Exclude = &gitHubConfigCriteria{Teams: []string{"legacy"}}
+
+	kept, rejected := findTeamFilteredRepos(t, cfg)
+	t.Equal([]string{"cli"}, kept)
+	t.Equal([]string{"excluded by team legacy", "repo isn't in any team in [tools]"}, rejected)
+}
+
+func (*gitHubTeamsTests) Org_criteria_override_teams(t *testgroup.T) {
+	cfg := &gitHubConfig{
+		Server: "ghes.example.com",
+		Orgs: map[string]*gitHubConfigCriteriaWithExclusions{
+			"bloomberg": {gitHubConfigCriteria: gitHubConfigCriteria{Teams: []string{"legacy"}}},
+		},
+	}
+	cfg.Teams = []string{"tools"}
+
+	kept, _ := findTeamFilteredRepos(t, cfg)
+	t.Equal([]string{"bloomberg/api"}, kept)
+}
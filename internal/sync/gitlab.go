@@ -0,0 +1,334 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mikesep/frond/internal/gitlab"
+)
+
+type gitLabConfig struct {
+	Server string `yaml:"server"`
+
+	SingleGroup string                           `yaml:"group,omitempty"`
+	Groups      map[string]*gitLabConfigCriteria `yaml:"groups,omitempty"`
+
+	gitLabConfigCriteria `yaml:",inline"`
+
+	Subgroups *bool `yaml:"subgroups,omitempty"` // include nested subgroups (default: true)
+
+	CloneProtocol string `yaml:"cloneProtocol,omitempty"` // https (default) or ssh
+
+	Auth *authConfig `yaml:"auth,omitempty"`
+}
+
+type gitLabConfigCriteria struct {
+	Names      []string `yaml:"names,omitempty"`
+	Topics     []string `yaml:"topics,omitempty"`
+	Visibility []string `yaml:"visibility,omitempty"`
+
+	Archived *bool `yaml:"archived,omitempty"`
+	Fork     *bool `yaml:"fork,omitempty"`
+
+	// compiled from the patterns above by compileFilters
+	names, topics, visibility filter
+}
+
+func (c *gitLabConfigCriteria) compileFilters() error {
+	var err error
+
+	if c.names, err = compileFieldFilter("names", c.Names, false); err != nil {
+		return err
+	}
+	if c.topics, err = compileFieldFilter("topics", c.Topics, false); err != nil {
+		return err
+	}
+	if c.visibility, err = compileFieldFilter("visibility", c.Visibility, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func (cfg *gitLabConfig) validate() error {
+	if cfg.Server == "" {
+		return fmt.Errorf("server is missing")
+	}
+
+	switch {
+	case cfg.SingleGroup != "" && len(cfg.Groups) != 0:
+		return fmt.Errorf("cannot have group and groups")
+	case cfg.SingleGroup == "" && len(cfg.Groups) == 0:
+		return fmt.Errorf("group or groups is missing")
+	}
+
+	if err := cfg.compileFilters(); err != nil {
+		return err
+	}
+
+	if err := validateCloneProtocol(cfg.CloneProtocol); err != nil {
+		return err
+	}
+
+	for _, v := range cfg.allVisibilities() {
+		switch v {
+		case "private", "internal", "public":
+		default:
+			return fmt.Errorf("unknown visibility %q (expected private, internal, or public)", v)
+		}
+	}
+
+	return nil
+}
+
+// compileFilters compiles the filters of every set of criteria, which validate also does.
+func (cfg *gitLabConfig) compileFilters() error {
+	criteria := []*gitLabConfigCriteria{&cfg.gitLabConfigCriteria}
+	for _, c := range cfg.Groups {
+		if c != nil {
+			criteria = append(criteria, c)
+		}
+	}
+
+	for _, c := range criteria {
+		if err := c.compileFilters(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cfg gitLabConfig) allVisibilities() []string {
+	all := cfg.Visibility
+	for _, criteria := range cfg.Groups {
+		if criteria != nil {
+			all = append(all, criteria.Visibility...)
+		}
+	}
+	return all
+}
+
+func (cfg gitLabConfig) groupNames() []string {
+	if cfg.SingleGroup != "" {
+		return []string{cfg.SingleGroup}
+	}
+
+	names := make([]string, 0, len(cfg.Groups))
+	for name := range cfg.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (cfg gitLabConfig) includeSubgroups() bool {
+	return cfg.Subgroups == nil || *cfg.Subgroups
+}
+
+// groupForProject returns the most specific configured group that contains the project.
+func (cfg gitLabConfig) groupForProject(project gitlab.Project) string {
+	var best string
+	for _, group := range cfg.groupNames() {
+		ns := project.Namespace.FullPath
+		if (ns == group || strings.HasPrefix(ns, group+"/")) && len(group) > len(best) {
+			best = group
+		}
+	}
+	return best
+}
+
+// pathForProject mirrors the project's namespace on disk. With a single group, paths are
+// relative to that group.
+func (cfg gitLabConfig) pathForProject(project gitlab.Project) string {
+	path := project.PathWithNamespace
+	if cfg.SingleGroup != "" {
+		path = strings.TrimPrefix(path, cfg.SingleGroup+"/")
+	}
+	return filepath.FromSlash(path)
+}
+
+func (cfg gitLabConfig) filterProject(project gitlab.Project) (rejectionReason string) {
+	groupCriteria := cfg.Groups[cfg.groupForProject(project)]
+
+	names, namesFilter := cfg.Names, cfg.names
+	if groupCriteria != nil && len(groupCriteria.Names) > 0 {
+		names, namesFilter = groupCriteria.Names, groupCriteria.names
+	}
+	if !namesFilter.matches(project.Path) {
+		return fmt.Sprintf("%s doesn't match any name in %v", project.Path, names)
+	}
+
+	topics, topicsFilter := cfg.Topics, cfg.topics
+	if groupCriteria != nil && len(groupCriteria.Topics) > 0 {
+		topics, topicsFilter = groupCriteria.Topics, groupCriteria.topics
+	}
+	if !topicsFilter.matchesAny(project.Topics) {
+		return fmt.Sprintf("none of the project topics %v match any config topic %v",
+			project.Topics, topics)
+	}
+
+	visibility, visibilityFilter := cfg.Visibility, cfg.visibility
+	if groupCriteria != nil && len(groupCriteria.Visibility) > 0 {
+		visibility, visibilityFilter = groupCriteria.Visibility, groupCriteria.visibility
+	}
+	if !visibilityFilter.matches(project.Visibility) {
+		return fmt.Sprintf("visibility %s isn't any of %v", project.Visibility, visibility)
+	}
+
+	archived := cfg.Archived
+	if groupCriteria != nil && groupCriteria.Archived != nil {
+		archived = groupCriteria.Archived
+	}
+	if archived != nil && project.Archived != *archived {
+		isOrIsNot := "is"
+		if !project.Archived {
+			isOrIsNot = "is not"
+		}
+		return fmt.Sprintf("project %s archived", isOrIsNot)
+	}
+
+	fork := cfg.Fork
+	if groupCriteria != nil && groupCriteria.Fork != nil {
+		fork = groupCriteria.Fork
+	}
+	if fork != nil && project.Fork() != *fork {
+		isOrIsNot := "is"
+		if !project.Fork() {
+			isOrIsNot = "is not"
+		}
+		return fmt.Sprintf("project %s a fork", isOrIsNot)
+	}
+
+	return ""
+}
+
+//------------------------------------------------------------------------------
+
+func findGitLabRepos(
+	syncRoot, workDir string, cmdArgs []string, cfg *gitLabConfig, listing listingOptions,
+	console io.Writer,
+) (idealRepoMap, rejectionReasonMap, error) {
+	ctx := context.Background()
+
+	absTargets, err := absTargetsInsideRoot(syncRoot, workDir, cmdArgs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	token, err := cfg.Auth.token("gitlab", cfg.Server, listing)
+	if err != nil {
+		return nil, nil, err
+	}
+	glSAT := gitlab.ServerAndToken{
+		Server: cfg.Server,
+		Token:  token,
+	}
+
+	var unfilteredProjects []gitlab.Project
+
+	for _, group := range cfg.groupNames() {
+		fmt.Fprintf(console, "Finding projects in %s/%s..", cfg.Server, group)
+		pp, err := glSAT.ListGroupProjects(ctx, console, group, cfg.includeSubgroups())
+		if err != nil {
+			fmt.Fprintf(console, " FAILED!\n")
+			return nil, nil, err
+		}
+		fmt.Fprintf(console, " found %d.\n", len(pp))
+
+		unfilteredProjects = append(unfilteredProjects, pp...)
+	}
+
+	protocol, err := preferredCloneProtocol(cfg.CloneProtocol)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	idealRepos := idealRepoMap{}
+	rejectedRepos := rejectionReasonMap{}
+
+	for _, p := range unfilteredProjects {
+		compURL, err := comparableRepoURL(p.HTTPURLToRepo)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		absPath := filepath.Join(syncRoot, cfg.pathForProject(p))
+		if !isUnderAnyTarget(absPath, absTargets) {
+			continue
+		}
+
+		if reason := cfg.filterProject(p); reason != "" {
+			rejectedRepos[compURL] = reason
+			continue
+		}
+
+		pathToRepo, err := filepath.Rel(workDir, absPath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		idealRepos[compURL] = idealRepo{
+			Path:          pathToRepo,
+			URL:           pickCloneURL(protocol, p.HTTPURLToRepo, p.SSHURLToRepo),
+			DefaultBranch: p.DefaultBranch,
+		}
+	}
+
+	fmt.Fprintf(console, "Filtered out %d and kept %d.\n", len(rejectedRepos), len(idealRepos))
+
+	return idealRepos, rejectedRepos, nil
+}
+
+// absTargetsInsideRoot returns nil when the args cover the whole sync root.
+func absTargetsInsideRoot(syncRoot, workDir string, cmdArgs []string) ([]string, error) {
+	var absTargets []string
+
+	for _, arg := range cmdArgs {
+		argPath := arg
+		if !filepath.IsAbs(arg) {
+			argPath = filepath.Join(workDir, arg)
+		}
+		argPath = filepath.Clean(argPath)
+
+		relToRoot, err := filepath.Rel(syncRoot, argPath)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(relToRoot, "..") {
+			return nil, fmt.Errorf("arg %q points outside sync root %q", arg, syncRoot)
+		}
+		if relToRoot == "." {
+			return nil, nil
+		}
+
+		absTargets = append(absTargets, argPath)
+	}
+
+	return absTargets, nil
+}
+
+// isUnderAnyTarget is true when there are no targets, or when path is a target, is inside one,
+// or contains one.
+func isUnderAnyTarget(path string, absTargets []string) bool {
+	if len(absTargets) == 0 {
+		return true
+	}
+
+	sep := string(filepath.Separator)
+	for _, t := range absTargets {
+		if path == t || strings.HasPrefix(path, t+sep) || strings.HasPrefix(t, path+sep) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bloomberg/go-testgroup"
+	"github.com/mikesep/frond/internal/gitlab"
+)
+
+func Test_GitLab(t *testing.T) {
+	testgroup.RunInParallel(t, &gitLabTests{})
+}
+
+type gitLabTests struct{}
+
+func newGitLabProject(namespace, path string) gitlab.Project {
+	return gitlab.Project{
+		Path:              path,
+		PathWithNamespace: namespace + "/" + path,
+		Namespace:         gitlab.Namespace{FullPath: namespace},
+		Visibility:        "private",
+	}
+}
+
+func (grp *gitLabTests) Paths_mirror_subgroups(t *testgroup.T) {
+	single := gitLabConfig{SingleGroup: "platform"}
+	t.Equal("api", single.pathForProject(newGitLabProject("platform", "api")))
+	t.Equal(filepath.Join("infra", "tools"),
+		single.pathForProject(newGitLabProject("platform/infra", "tools")))
+
+	many := gitLabConfig{Groups: map[string]*gitLabConfigCriteria{"platform": nil, "data": nil}}
+	t.Equal(filepath.Join("platform", "infra", "tools"),
+		many.pathForProject(newGitLabProject("platform/infra", "tools")))
+}
+
+func (grp *gitLabTests) Most_specific_group_criteria_win(t *testgroup.T) {
+	cfg := gitLabConfig{
+		Groups: map[string]*gitLabConfigCriteria{
+			"platform":       nil,
+			"platform/infra": {Names: []string{"tools"}},
+		},
+	}
+
+	t.Require.NoError(cfg.compileFilters())
+
+	t.Equal("platform/infra", cfg.groupForProject(newGitLabProject("platform/infra/deep", "x")))
+	t.Equal("platform", cfg.groupForProject(newGitLabProject("platform/infrastructure", "x")))
+
+	reason := cfg.filterProject(newGitLabProject("platform/infra", "tools"))
+	t.Empty(reason)
+
+	reason = cfg.filterProject(newGitLabProject("platform/infra", "other"))
+	t.Equal("other doesn't match any name in [tools]", reason)
+
+	reason = cfg.filterProject(newGitLabProject("platform", "other"))
+	t.Empty(reason)
+}
+
+func (grp *gitLabTests) Filter_visibility_archived_fork(t *testgroup.T) {
+	cfg := gitLabConfig{
+		SingleGroup: "platform",
+		gitLabConfigCriteria: gitLabConfigCriteria{
+			Visibility: []string{"internal"},
+			Archived:   &falseVar,
+			Fork:       &falseVar,
+		},
+	}
+
+	t.Require.NoError(cfg.compileFilters())
+
+	p := newGitLabProject("platform", "api")
+	reason := cfg.filterProject(p)
+	t.Equal("visibility private isn't any of [internal]", reason)
+
+	p.Visibility = "internal"
+	p.Archived = true
+	reason = cfg.filterProject(p)
+	t.Equal("project is archived", reason)
+
+	p.Archived = false
+	p.ForkedFromProject = &gitlab.ProjectRef{ID: 1}
+	reason = cfg.filterProject(p)
+	t.Equal("project is a fork", reason)
+}
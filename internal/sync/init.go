@@ -10,12 +10,15 @@ import (
 	"os"
 	"strings"
 
-	"github.com/mikesep/frond/internal/git"
+	"github.com/mikesep/frond/internal/bitbucket"
+	"github.com/mikesep/frond/internal/gitea"
 	"github.com/mikesep/frond/internal/github"
+	"github.com/mikesep/frond/internal/gitlab"
 )
 
 type InitOptions struct {
-	Force bool `short:"f" long:"force" description:"Force init if config already exists."`
+	Force bool   `short:"f" long:"force" description:"Force init if config already exists."`
+	Auth  string `long:"auth" value-name:"TYPE" choice:"git" choice:"env" choice:"gh" description:"Where to get API tokens, now and in the config. (default: git's credential helper, then environment variables)"`
 
 	GitHub gitHubInitOptions
 }
@@ -29,7 +32,8 @@ func (opts *InitOptions) Execute(args []string) error {
 		return fmt.Errorf("missing arguments")
 	}
 
-	var server string
+	var servers []string
+	argsByServer := map[string][]string{}
 
 	for _, arg := range args {
 		u, err := url.Parse(arg)
@@ -40,26 +44,28 @@ func (opts *InitOptions) Execute(args []string) error {
 			return fmt.Errorf("parse %q, but u.Host is empty: %#v", arg, u)
 		}
 
-		switch {
-		case server == "":
-			server = u.Host
-		case server != u.Host:
-			return fmt.Errorf("cannot init with multiple servers: %q, %q", server, u.Host)
+		if _, ok := argsByServer[u.Host]; !ok {
+			servers = append(servers, u.Host)
 		}
+		argsByServer[u.Host] = append(argsByServer[u.Host], arg)
 	}
 
 	var cfg syncConfig
 
-	switch {
-	case server == "github.com", github.DetectEnterpriseServer(server):
-		gitHubCfg, err := opts.GitHub.newConfig(server, args)
+	for _, server := range servers {
+		src, err := opts.newSourceConfig(server, argsByServer[server])
 		if err != nil {
 			return err
 		}
 
-		cfg.GitHub = gitHubCfg
-	default:
-		return fmt.Errorf("unrecognized server type for %q", server)
+		if len(servers) == 1 {
+			cfg.sourceConfig = src
+			break
+		}
+
+		// Give each server its own dir.
+		src.Dir = server
+		cfg.Sources = append(cfg.Sources, src)
 	}
 
 	if err := writeConfig(cfg); err != nil {
@@ -72,12 +78,100 @@ func (opts *InitOptions) Execute(args []string) error {
 	return nil
 }
 
+func (opts *InitOptions) newSourceConfig(server string, args []string) (sourceConfig, error) {
+	var src sourceConfig
+
+	ctx := context.Background()
+
+	var section string
+	switch {
+	case server == "github.com", github.DetectEnterpriseServer(server):
+		section = "github"
+	case gitlab.DetectServer(ctx, nil, server):
+		section = "gitlab"
+	case gitea.DetectServer(ctx, nil, server):
+		section = "gitea"
+	case bitbucket.DetectServer(ctx, nil, server):
+		section = "bitbucket"
+	default:
+		return src, fmt.Errorf("unrecognized server type for %q", server)
+	}
+
+	authCfg, err := opts.authConfig(section)
+	if err != nil {
+		return src, err
+	}
+
+	workDir, err := os.Getwd()
+	if err != nil {
+		return src, err
+	}
+
+	// Get the token the same way that sync will.
+	token, err := authCfg.token(section, server, listingOptions{syncRoot: workDir})
+	if err != nil {
+		return src, err
+	}
+
+	switch section {
+	case "github":
+		src.GitHub, err = opts.GitHub.newConfig(server, args, token)
+		if err == nil {
+			src.GitHub.Auth = authCfg
+		}
+	case "gitlab":
+		src.GitLab, err = newGitLabConfig(server, args, token)
+		if err == nil {
+			src.GitLab.Auth = authCfg
+		}
+	case "gitea":
+		src.Gitea, err = opts.GitHub.newGiteaConfig(server, args, token)
+		if err == nil {
+			src.Gitea.Auth = authCfg
+		}
+	case "bitbucket":
+		src.Bitbucket, err = newBitbucketConfig(server, args, token)
+		if err == nil {
+			src.Bitbucket.Auth = authCfg
+		}
+	}
+
+	return src, err
+}
+
+// authConfig is what --auth asks for, or nil for the default.
+func (opts *InitOptions) authConfig(section string) (*authConfig, error) {
+	if opts.Auth == "" {
+		return nil, nil
+	}
+
+	cfg := &authConfig{Type: opts.Auth}
+	if err := cfg.validate(section); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
 type gitHubInitOptions struct {
 	SingleDir              *bool   `long:"single-dir" description:"Use a single dir for all repositories."`
 	AccountPrefixSeparator *string `long:"account-prefix-separator" value-name:"SEP" description:"Create dirs named like <account><SEP><repository>"`
 }
 
-func (opts *gitHubInitOptions) newConfig(server string, args []string) (*gitHubConfig, error) {
+func (opts *gitHubInitOptions) newConfig(server string, args []string, token string,
+) (*gitHubConfig, error) {
+	ghSAT := github.ServerAndToken{
+		Server: server,
+		Token:  token,
+	}
+
+	return opts.newConfigFromAccounts(server, args, ghSAT)
+}
+
+// newConfigFromAccounts adds each arg's account to the config (as a whole, or narrowed down to
+// the named repositories), and then moves a lone account up to the top level.
+func (opts *gitHubInitOptions) newConfigFromAccounts(
+	server string, args []string, ghSAT accountRepoLister,
+) (*gitHubConfig, error) {
 	cfg := &gitHubConfig{
 		Server: server,
 		Orgs:   map[string]*gitHubConfigCriteriaWithExclusions{},
@@ -87,16 +181,6 @@ func (opts *gitHubInitOptions) newConfig(server string, args []string) (*gitHubC
 	cfg.SingleDirForAllRepos = opts.SingleDir
 	cfg.AccountPrefixSeparator = opts.AccountPrefixSeparator
 
-	cred, err := git.FillCredential("https", cfg.Server)
-	if err != nil {
-		return nil, err
-	}
-
-	ghSAT := github.ServerAndToken{
-		Server: cfg.Server,
-		Token:  cred.Password,
-	}
-
 	for _, arg := range args {
 		u, err := url.Parse(arg)
 		if err != nil {
@@ -164,3 +248,68 @@ func (opts *gitHubInitOptions) newConfig(server string, args []string) (*gitHubC
 
 	return cfg, nil
 }
+
+// newGitLabConfig treats each arg as a group (including its subgroups), unless it's a project.
+func newGitLabConfig(server string, args []string, token string) (*gitLabConfig, error) {
+	cfg := &gitLabConfig{
+		Server: server,
+		Groups: map[string]*gitLabConfigCriteria{},
+	}
+
+	glSAT := gitlab.ServerAndToken{
+		Server: cfg.Server,
+		Token:  token,
+	}
+
+	ctx := context.Background()
+
+	for _, arg := range args {
+		u, err := url.Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %w", arg, err)
+		}
+
+		fullPath := strings.Trim(strings.TrimSuffix(u.Path, ".git"), "/")
+		if fullPath == "" {
+			return nil, fmt.Errorf("expected GL/group or GL/group/project (%q)", arg)
+		}
+
+		if group, err := glSAT.GetGroup(ctx, fullPath); err == nil {
+			if cfg.Groups[group.FullPath] != nil {
+				return nil, fmt.Errorf("cannot init both at group and project level for %q",
+					group.FullPath)
+			}
+			cfg.Groups[group.FullPath] = nil
+			continue
+		}
+
+		project, err := glSAT.GetProject(ctx, fullPath)
+		if err != nil {
+			return nil, fmt.Errorf("%q is neither a group nor a project: %w", fullPath, err)
+		}
+
+		groupPath := project.Namespace.FullPath
+		criteria, ok := cfg.Groups[groupPath]
+		if ok && criteria == nil {
+			return nil, fmt.Errorf("cannot init both at group and project level for %q",
+				groupPath)
+		}
+		if !ok {
+			criteria = &gitLabConfigCriteria{}
+			cfg.Groups[groupPath] = criteria
+		}
+		criteria.Names = append(criteria.Names, project.Path)
+	}
+
+	if len(cfg.Groups) == 1 {
+		for name, criteria := range cfg.Groups {
+			cfg.SingleGroup = name
+			if criteria != nil {
+				cfg.gitLabConfigCriteria = *criteria
+			}
+			delete(cfg.Groups, name)
+		}
+	}
+
+	return cfg, nil
+}
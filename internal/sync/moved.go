@@ -0,0 +1,155 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/mikesep/frond/internal/git"
+	"github.com/mikesep/frond/internal/github"
+	giturls "github.com/whilp/git-urls"
+)
+
+// movedRepoResolver finds the URL that a renamed or transferred repo has now. It returns "" for
+// repos that haven't moved, or that it doesn't know about.
+type movedRepoResolver func(oldURL string) (newURL string, err error)
+
+// firstMovedRepoResolver asks each resolver in turn until one knows where the repo went.
+func firstMovedRepoResolver(resolvers []movedRepoResolver) movedRepoResolver {
+	return func(oldURL string) (string, error) {
+		for _, resolve := range resolvers {
+			newURL, err := resolve(oldURL)
+			if err != nil || newURL != "" {
+				return newURL, err
+			}
+		}
+		return "", nil
+	}
+}
+
+// movedRepoResolver is nil for sources that can't tell where repos went. Only GitHub can, since
+// it redirects requests for a repo's old name. Nothing is requested until a repo needs resolving.
+func (src sourceConfig) movedRepoResolver(listing listingOptions) movedRepoResolver {
+	if src.GitHub == nil || listing.offline {
+		return nil
+	}
+
+	cfg := src.GitHub
+	var sat *github.ServerAndToken
+
+	return newGitHubMovedRepoResolver(cfg.Server,
+		func(ctx context.Context, fullName string) (github.Repo, error) {
+			if sat == nil {
+				token, gitCredential, err := cfg.cachedToken(listing)
+				if err != nil {
+					return github.Repo{}, err
+				}
+				sat = &github.ServerAndToken{
+					Server:        cfg.Server,
+					Token:         token,
+					GitCredential: gitCredential,
+				}
+			}
+			return sat.GetRepo(ctx, fullName)
+		})
+}
+
+func newGitHubMovedRepoResolver(server string,
+	getRepo func(ctx context.Context, fullName string) (github.Repo, error),
+) movedRepoResolver {
+	return func(oldURL string) (string, error) {
+		u, err := giturls.Parse(oldURL)
+		if err != nil || u.Host != server {
+			return "", nil
+		}
+
+		fullName := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+		if strings.Count(fullName, "/") != 1 {
+			return "", nil
+		}
+
+		repo, err := getRepo(context.Background(), fullName)
+
+		var statusErr *github.StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			return "", nil // deleted, or we can't see it
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to check whether %s moved: %w", oldURL, err)
+		}
+
+		if strings.EqualFold(repo.FullName, fullName) {
+			return "", nil
+		}
+		return repo.CloneURL, nil
+	}
+}
+
+// findMovedRepo looks for a remote whose repo has moved to where the config wants it. It returns
+// nil if there isn't one.
+func findMovedRepo(localRepo git.LocalRepo, remotes git.LocalRepoRemotes,
+	idealRepos idealRepoMap, rejectionReasons rejectionReasonMap, resolve movedRepoResolver,
+) (syncAction, error) {
+	if resolve == nil {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(remotes))
+	for name := range remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, remoteName := range names {
+		newURL, err := resolve(remotes[remoteName].FetchURL)
+		if err != nil {
+			return nil, err
+		}
+		if newURL == "" {
+			continue
+		}
+
+		compURL, err := comparableRepoURL(newURL)
+		if err != nil {
+			return nil, err
+		}
+
+		ideal, ok := idealRepos[compURL]
+		if !ok {
+			if reason, ok := rejectionReasons[compURL]; ok {
+				return actionRemoveRepo{
+					Path:   localRepo.Root,
+					Reason: fmt.Sprintf("moved to %s, but %s", compURL, reason),
+				}, nil
+			}
+			continue
+		}
+		delete(idealRepos, compURL)
+
+		defaultTrackingBranch, err := defaultTrackingBranch(localRepo, remoteName, ideal)
+		if err != nil {
+			return nil, err
+		}
+
+		upstreamURL, err := upstreamToAdd(remotes, ideal)
+		if err != nil {
+			return nil, err
+		}
+
+		return actionMoveAndSyncRepo{
+			OrigPath:              localRepo.Root,
+			DestPath:              ideal.Path,
+			DefaultTrackingBranch: defaultTrackingBranch,
+			remoteRewrite:         remoteRewrite{RemoteName: remoteName, RemoteURL: ideal.URL},
+			UpstreamURL:           upstreamURL,
+		}, nil
+	}
+
+	return nil, nil
+}
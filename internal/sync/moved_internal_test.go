@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bloomberg/go-testgroup"
+	"github.com/mikesep/frond/internal/action"
+	"github.com/mikesep/frond/internal/git"
+	"github.com/mikesep/frond/internal/github"
+)
+
+func Test_MovedRepos(t *testing.T) {
+	testgroup.RunInParallel(t, &movedRepoTests{})
+}
+
+type movedRepoTests struct{}
+
+func (*movedRepoTests) GitHub_resolver_follows_renames(t *testgroup.T) {
+	var asked []string
+	resolve := newGitHubMovedRepoResolver("github.com",
+		func(ctx context.Context, fullName string) (github.Repo, error) {
+			asked = append(asked, fullName)
+			switch fullName {
+			case "bloomberg/old-name":
+				return github.Repo{
+					FullName: "tools/new-name",
+					CloneURL: "https://github.com/tools/new-name.git",
+				}, nil
+			case "bloomberg/same":
+				return github.Repo{FullName: "Bloomberg/same"}, nil
+			case "bloomberg/gone":
+				return github.Repo{}, &github.StatusError{StatusCode: 404}
+			}
+			return github.Repo{}, errors.New("boom")
+		})
+
+	for oldURL, want := range map[string]string{
+		"https://github.com/bloomberg/old-name.git": "https://github.com/tools/new-name.git",
+		"git@github.com:bloomberg/old-name.git":     "https://github.com/tools/new-name.git",
+		"https://github.com/bloomberg/same.git":     "",
+		"https://github.com/bloomberg/gone.git":     "",
+		"https://gitlab.com/bloomberg/old-name.git": "", // not asked
+	} {
+		newURL, err := resolve(oldURL)
+		t.NoError(err, oldURL)
+		t.Equal(want, newURL, oldURL)
+	}
+	t.Len(asked, 4)
+
+	_, err := resolve("https://github.com/bloomberg/broken.git")
+	t.Error(err)
+}
+
+func (*movedRepoTests) Renamed_repo_is_moved_and_its_remote_updated(t *testgroup.T) {
+	upstream, clone := newClone(t)
+
+	renamed := upstream + "-renamed"
+	t.Require.NoError(os.Rename(upstream, renamed))
+
+	compURL, err := comparableRepoURL(renamed)
+	t.Require.NoError(err)
+
+	dest := filepath.Join(filepath.Dir(clone), "tools", "renamed")
+	idealRepos := idealRepoMap{compURL: {Path: dest, URL: renamed, DefaultBranch: "main"}}
+
+	resolve := func(oldURL string) (string, error) {
+		if oldURL == upstream {
+			return renamed, nil
+		}
+		return "", nil
+	}
+
+	a, err := matchRepoToAction(clone, idealRepos, nil, resolve, "origin")
+	t.Require.NoError(err)
+	t.Equal(actionMoveAndSyncRepo{
+		OrigPath:              clone,
+		DestPath:              dest,
+		DefaultTrackingBranch: "origin/main",
+		remoteRewrite:         remoteRewrite{RemoteName: "origin", RemoteURL: renamed},
+	}, a)
+	t.Empty(idealRepos, "the moved repo shouldn't be cloned again")
+
+	event := a.Do(&Options{DryRun: true})
+	t.Equal("would point origin at "+renamed+", move to "+dest+", and sync", event.Message)
+
+	event = a.Do(&Options{})
+	t.NotEqual(action.Failed, event.Type, event.Message)
+
+	localRepo := git.LocalRepo{Root: dest}
+	remotes, err := localRepo.Remotes()
+	t.Require.NoError(err)
+	t.Equal(renamed, remotes["origin"].FetchURL)
+
+	_, err = os.Stat(clone)
+	t.True(os.IsNotExist(err))
+}
+
+func (*movedRepoTests) Moved_to_a_rejected_repo_is_removed(t *testgroup.T) {
+	_, clone := newClone(t)
+
+	resolve := func(string) (string, error) { return "https://github.com/tools/new-name.git", nil }
+
+	a, err := matchRepoToAction(clone, idealRepoMap{}, rejectionReasonMap{
+		"github.com/tools/new-name": "repo is archived",
+	}, resolve, "origin")
+	t.Require.NoError(err)
+	t.Equal(actionRemoveRepo{
+		Path:   clone,
+		Reason: "moved to github.com/tools/new-name, but repo is archived",
+	}, a)
+}
+
+func (*movedRepoTests) Unresolved_repo_is_removed(t *testgroup.T) {
+	_, clone := newClone(t)
+
+	a, err := matchRepoToAction(clone, idealRepoMap{}, nil,
+		func(string) (string, error) { return "", nil }, "origin")
+	t.Require.NoError(err)
+	t.Equal(actionRemoveRepo{Path: clone, Reason: "did not match any remote repo URL"}, a)
+}
@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"fmt"
+	"os"
+
+	giturls "github.com/whilp/git-urls"
+)
+
+// cloneProtocolEnvVar lets each user override the clone protocol in a shared config.
+const cloneProtocolEnvVar = "FROND_CLONE_PROTOCOL"
+
+func validateCloneProtocol(protocol string) error {
+	switch protocol {
+	case "", "https", "ssh":
+		return nil
+	}
+	return fmt.Errorf("unknown cloneProtocol %q (expected https or ssh)", protocol)
+}
+
+// preferredCloneProtocol is https or ssh: the configured protocol, unless the environment says
+// otherwise.
+func preferredCloneProtocol(configured string) (string, error) {
+	protocol := configured
+	if env := os.Getenv(cloneProtocolEnvVar); env != "" {
+		if err := validateCloneProtocol(env); err != nil {
+			return "", fmt.Errorf("%s: %w", cloneProtocolEnvVar, err)
+		}
+		protocol = env
+	}
+
+	if protocol == "" {
+		return "https", nil
+	}
+	return protocol, nil
+}
+
+// pickCloneURL falls back to the HTTPS URL when there's no SSH one.
+func pickCloneURL(protocol, httpsURL, sshURL string) string {
+	if protocol == "ssh" && sshURL != "" {
+		return sshURL
+	}
+	return httpsURL
+}
+
+func isSSHURL(rawURL string) bool {
+	u, err := giturls.Parse(rawURL)
+	return err == nil && u.Scheme == "ssh"
+}
@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bloomberg/go-testgroup"
+	"github.com/mikesep/frond/internal/action"
+	"github.com/mikesep/frond/internal/git"
+)
+
+func Test_CloneProtocol(t *testing.T) {
+	testgroup.RunSerially(t, &cloneProtocolTests{}) // the tests set environment variables
+}
+
+type cloneProtocolTests struct{}
+
+func (*cloneProtocolTests) Defaults_to_https(t *testgroup.T) {
+	t.Setenv(cloneProtocolEnvVar, "")
+
+	protocol, err := preferredCloneProtocol("")
+	t.NoError(err)
+	t.Equal("https", protocol)
+
+	protocol, err = preferredCloneProtocol("ssh")
+	t.NoError(err)
+	t.Equal("ssh", protocol)
+}
+
+func (*cloneProtocolTests) Environment_overrides_config(t *testgroup.T) {
+	t.Setenv(cloneProtocolEnvVar, "ssh")
+
+	protocol, err := preferredCloneProtocol("https")
+	t.NoError(err)
+	t.Equal("ssh", protocol)
+
+	t.Setenv(cloneProtocolEnvVar, "git")
+
+	_, err = preferredCloneProtocol("https")
+	t.Error(err)
+	t.Contains(err.Error(), cloneProtocolEnvVar)
+}
+
+func (*cloneProtocolTests) Picks_clone_URLs(t *testgroup.T) {
+	const httpsURL, sshURL = "https://github.com/a/b.git", "git@github.com:a/b.git"
+
+	t.Equal(httpsURL, pickCloneURL("https", httpsURL, sshURL))
+	t.Equal(sshURL, pickCloneURL("ssh", httpsURL, sshURL))
+	t.Equal(httpsURL, pickCloneURL("ssh", httpsURL, ""))
+}
+
+func (*cloneProtocolTests) Both_protocols_are_the_same_repo(t *testgroup.T) {
+	for httpsURL, sshURL := range map[string]string{
+		"https://github.com/a/b.git":                 "git@github.com:a/b.git",
+		"https://gitlab.example.com/group/sub/p.git": "git@gitlab.example.com:group/sub/p.git",
+		"https://bitbucket.example.com/scm/K/r.git":  "ssh://git@bitbucket.example.com:7999/K/r.git",
+		"https://bitbucket.example.com:8443/scm/K/r": "ssh://git@bitbucket.example.com:7999/K/r.git",
+	} {
+		fromHTTPS, err := comparableRepoURL(httpsURL)
+		t.Require.NoError(err)
+		fromSSH, err := comparableRepoURL(sshURL)
+		t.Require.NoError(err)
+
+		t.Equal(fromHTTPS, fromSSH, httpsURL)
+		t.False(isSSHURL(httpsURL), httpsURL)
+		t.True(isSSHURL(sshURL), sshURL)
+	}
+}
+
+func (*cloneProtocolTests) Matching_notes_a_protocol_change(t *testgroup.T) {
+	_, clone := newClone(t)
+	runGit(t, clone, "remote", "set-url", "origin", "git@github.com:a/b.git")
+
+	idealRepos := idealRepoMap{
+		"github.com/a/b": {Path: clone, URL: "https://github.com/a/b.git", DefaultBranch: "main"},
+	}
+
+	a, err := matchRepoToAction(clone, idealRepos, rejectionReasonMap{}, nil, "origin")
+	t.Require.NoError(err)
+	t.Equal(actionSyncRepo{
+		Path:                  clone,
+		DefaultTrackingBranch: "origin/main",
+		remoteRewrite: remoteRewrite{
+			RemoteName:   "origin",
+			RemoteURL:    "https://github.com/a/b.git",
+			ProtocolOnly: true,
+		},
+	}, a)
+}
+
+func (*cloneProtocolTests) Remotes_are_only_rewritten_on_request(t *testgroup.T) {
+	upstream, clone := newClone(t)
+	runGit(t, clone, "remote", "set-url", "origin", "git@github.com:a/b.git")
+
+	a := actionSyncRepo{
+		Path:                  clone,
+		DefaultTrackingBranch: "origin/main",
+		remoteRewrite: remoteRewrite{
+			RemoteName:   "origin",
+			RemoteURL:    upstream, // so that the sync can fetch
+			ProtocolOnly: true,
+		},
+	}
+
+	event := a.Do(&Options{DryRun: true})
+	t.Equal("would sync", event.Message)
+
+	event = a.Do(&Options{DryRun: true, RewriteRemotes: true})
+	t.True(strings.HasPrefix(event.Message, "would point origin at "), event.Message)
+
+	event = a.Do(&Options{RewriteRemotes: true})
+	t.NotEqual(action.Failed, event.Type, event.Message)
+
+	localRepo := git.LocalRepo{Root: clone}
+	remotes, err := localRepo.Remotes()
+	t.Require.NoError(err)
+	t.Equal(upstream, remotes["origin"].FetchURL)
+}
@@ -0,0 +1,185 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mikesep/frond/internal/git"
+)
+
+// findUnsyncedWork returns a description of each kind of work in the repo that would be lost if
+// it were removed: uncommitted or untracked files, stashes, and commits or tags that aren't on
+// any remote.
+func findUnsyncedWork(repoPath string) ([]string, error) {
+	repo := git.LocalRepo{Root: repoPath}
+
+	var unsynced []string
+
+	status, err := git.GetStatus(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if status.ChangedOrRenamed {
+		unsynced = append(unsynced, "uncommitted changes")
+	}
+	if status.Unmerged {
+		unsynced = append(unsynced, "unmerged files")
+	}
+	if status.Untracked {
+		unsynced = append(unsynced, "untracked files")
+	}
+	if status.Stashed {
+		unsynced = append(unsynced, "stashed changes")
+	}
+
+	branches, _, err := repo.LocalBranches()
+	if err != nil {
+		return nil, err
+	}
+
+	var unpushedBranches []string
+	for branch := range branches {
+		unpushed, err := repo.HasUnpushedCommits(branch)
+		if err != nil {
+			return nil, err
+		}
+		if unpushed {
+			unpushedBranches = append(unpushedBranches, branch)
+		}
+	}
+	if len(unpushedBranches) > 0 {
+		sort.Strings(unpushedBranches)
+		unsynced = append(unsynced,
+			fmt.Sprintf("unpushed commits on %s", strings.Join(unpushedBranches, ", ")))
+	}
+
+	if status.BranchHead == "(detached)" {
+		unpushed, err := repo.HasUnpushedCommits("HEAD")
+		if err != nil {
+			return nil, err
+		}
+		if unpushed {
+			unsynced = append(unsynced, "unpushed commits on detached HEAD")
+		}
+	}
+
+	tags, err := repo.UnpushedTags()
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 {
+		unsynced = append(unsynced, fmt.Sprintf("unpushed tags %s", strings.Join(tags, ", ")))
+	}
+
+	return unsynced, nil
+}
+
+// moveToTrash moves the repo under trashDir, mirroring its absolute path inside a directory
+// named for the current time, so nothing in the trash gets overwritten.
+func moveToTrash(repoPath, trashDir string) (string, error) {
+	absRepo, err := filepath.Abs(repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	dest := filepath.Join(trashDir, time.Now().Format("20060102-150405"), absRepo)
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return "", err
+	}
+
+	err = os.Rename(repoPath, dest)
+	if errors.Is(err, syscall.EXDEV) {
+		// The trash is on another filesystem, so the repo has to be copied there instead.
+		err = copyTree(repoPath, dest)
+		if err != nil {
+			os.RemoveAll(dest)
+			return "", fmt.Errorf("failed to copy to %s on another filesystem: %w", trashDir, err)
+		}
+		err = os.RemoveAll(repoPath)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return dest, nil
+}
+
+// checkTrashDir makes sure the trash is outside the sync root, where repos in it won't be seen
+// as local repos by later syncs.
+func checkTrashDir(trashDir, syncRoot string) error {
+	absTrash, err := filepath.Abs(trashDir)
+	if err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(syncRoot, absTrash)
+	if err != nil {
+		return nil // e.g. on another volume
+	}
+
+	if rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("--trash-dir %s is inside the sync root %s", trashDir, syncRoot)
+	}
+
+	return nil
+}
+
+// copyTree copies the directory src to dest, keeping file modes and symlinks.
+func copyTree(src, dest string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			return fmt.Errorf("can't copy %s: unsupported file type", path)
+		}
+	})
+}
+
+func copyFile(src, dest string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
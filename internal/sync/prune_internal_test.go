@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bloomberg/go-testgroup"
+)
+
+func Test_findUnsyncedWork(t *testing.T) {
+	testgroup.RunInParallel(t, &findUnsyncedWorkTests{})
+}
+
+type findUnsyncedWorkTests struct{}
+
+func (*findUnsyncedWorkTests) Clean_clone(t *testgroup.T) {
+	upstream, clone := newClone(t)
+	runGit(t, upstream, "tag", "v1")
+	runGit(t, clone, "fetch", "--quiet", "--tags")
+
+	unsynced, err := findUnsyncedWork(clone)
+	t.Require.NoError(err)
+	t.Empty(unsynced)
+}
+
+func (*findUnsyncedWorkTests) Everything_unsynced(t *testgroup.T) {
+	_, clone := newClone(t)
+
+	runGit(t, clone, "switch", "--quiet", "--create", "feature")
+	commitEmpty(t, clone, "local work")
+	runGit(t, clone, "tag", "local-tag")
+
+	t.Require.NoError(os.WriteFile(filepath.Join(clone, "stash-me"), []byte("x\n"), 0o644))
+	runGit(t, clone, "stash", "--quiet", "--include-untracked")
+	t.Require.NoError(os.WriteFile(filepath.Join(clone, "untracked"), []byte("x\n"), 0o644))
+
+	unsynced, err := findUnsyncedWork(clone)
+	t.Require.NoError(err)
+	t.Equal([]string{
+		"untracked files",
+		"stashed changes",
+		"unpushed commits on feature",
+		"unpushed tags local-tag",
+	}, unsynced)
+}
+
+func (*findUnsyncedWorkTests) Move_to_trash(t *testgroup.T) {
+	_, clone := newClone(t)
+	trash := t.TempDir()
+
+	dest, err := moveToTrash(clone, trash)
+	t.Require.NoError(err)
+
+	_, err = os.Stat(clone)
+	t.True(os.IsNotExist(err))
+	t.DirExists(filepath.Join(dest, ".git"))
+	t.Equal(trash, dest[:len(trash)])
+}
+
+func (*findUnsyncedWorkTests) Trash_must_be_outside_the_sync_root(t *testgroup.T) {
+	root := t.TempDir()
+
+	t.NoError(checkTrashDir(filepath.Join(filepath.Dir(root), "trash"), root))
+	t.NoError(checkTrashDir(filepath.Join(root, "..", "..trash"), root))
+	t.Error(checkTrashDir(root, root))
+	t.Error(checkTrashDir(filepath.Join(root, ".trash"), root))
+	t.Error(checkTrashDir(filepath.Join(root, "org", "..trash"), root))
+}
+
+func (*findUnsyncedWorkTests) Copy_a_repo_across_filesystems(t *testgroup.T) {
+	_, clone := newClone(t)
+	t.Require.NoError(os.WriteFile(filepath.Join(clone, "script"), []byte("#!/bin/sh\n"), 0o755))
+	t.Require.NoError(os.Symlink("script", filepath.Join(clone, "link")))
+
+	dest := filepath.Join(t.TempDir(), "copy")
+	t.Require.NoError(copyTree(clone, dest))
+
+	info, err := os.Stat(filepath.Join(dest, "script"))
+	t.Require.NoError(err)
+	t.Equal(os.FileMode(0o755), info.Mode().Perm())
+
+	link, err := os.Readlink(filepath.Join(dest, "link"))
+	t.Require.NoError(err)
+	t.Equal("script", link)
+
+	runGit(t, dest, "fsck", "--no-progress") // the copy is still a repo
+}
@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mikesep/frond/internal/git"
+	"github.com/mikesep/frond/internal/github"
+)
+
+const defaultRemoteName = "origin"
+
+// upstreamRemoteName is the remote that points at a fork's parent.
+const upstreamRemoteName = "upstream"
+
+func validateRemoteName(name string) error {
+	switch {
+	case name == upstreamRemoteName:
+		return fmt.Errorf("remoteName cannot be %q, which is used for forks' parents", name)
+	case strings.HasPrefix(name, "-") || strings.ContainsAny(name, " \t\n:?*[\\^~"):
+		return fmt.Errorf("bad remoteName %q", name)
+	}
+	return nil
+}
+
+// remoteName is what clones call the remote they were cloned from.
+func (cfg syncConfig) remoteName() string {
+	if cfg.RemoteName == "" {
+		return defaultRemoteName
+	}
+	return cfg.RemoteName
+}
+
+// findForkParent returns nil if the repo isn't a fork, or if its parent can't be found offline.
+// REST listings don't say what a fork's parent is, so the fork is looked up on its own.
+func findForkParent(ctx context.Context, lister accountRepoLister, repo github.Repo,
+) (*github.Repo, error) {
+	if !repo.Fork {
+		return nil, nil
+	}
+	if repo.Parent != nil {
+		return repo.Parent, nil
+	}
+
+	full, err := lister.GetRepo(ctx, repo.FullName)
+	if errors.Is(err, github.ErrNotCached) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the parent of %s: %w", repo.FullName, err)
+	}
+
+	return full.Parent, nil
+}
+
+// upstreamToAdd is the URL of the ideal repo's parent, unless the local repo already has an
+// upstream remote or a remote that points at the parent. The parent is only looked up when
+// there's no upstream remote.
+func upstreamToAdd(remotes git.LocalRepoRemotes, ideal idealRepo) (string, error) {
+	if _, ok := remotes[upstreamRemoteName]; ok {
+		return "", nil
+	}
+
+	upstreamURL, err := ideal.upstreamURL()
+	if err != nil || upstreamURL == "" {
+		return "", err
+	}
+
+	parentURL, err := comparableRepoURL(upstreamURL)
+	if err != nil {
+		return "", err
+	}
+	for _, remote := range remotes {
+		compURL, err := comparableRepoURL(remote.FetchURL)
+		if err != nil {
+			return "", err
+		}
+		if compURL == parentURL {
+			return "", nil
+		}
+	}
+
+	return upstreamURL, nil
+}
+
+func addUpstreamRemote(repoPath, url string) error {
+	localRepo := git.LocalRepo{Root: repoPath}
+	if err := localRepo.AddRemote(upstreamRemoteName, url); err != nil {
+		return fmt.Errorf("failed to add %s remote %s: %w", upstreamRemoteName, url, err)
+	}
+	return nil
+}
@@ -0,0 +1,306 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bloomberg/go-testgroup"
+	"github.com/mikesep/frond/internal/action"
+	"github.com/mikesep/frond/internal/git"
+	"github.com/mikesep/frond/internal/github"
+)
+
+func Test_Remotes(t *testing.T) {
+	testgroup.RunInParallel(t, &remotesTests{})
+}
+
+type remotesTests struct{}
+
+func (*remotesTests) Remote_name_is_validated(t *testgroup.T) {
+	cfg, err := parseConfig(strings.NewReader(
+		"remoteName: github\nrepos:\n- {url: https://x/y, path: y}\n"))
+	t.Require.NoError(err)
+	t.Equal("github", cfg.remoteName())
+	t.Equal("origin", syncConfig{}.remoteName())
+
+	for _, name := range []string{"upstream", "-f", "two words", "a:b"} {
+		t.Error(validateRemoteName(name), name)
+	}
+}
+
+// getRepoLister answers GetRepo, and nothing else.
+type getRepoLister struct {
+	accountRepoLister
+	repo github.Repo
+	err  error
+}
+
+func (l getRepoLister) GetRepo(ctx context.Context, fullName string) (github.Repo, error) {
+	return l.repo, l.err
+}
+
+func (*remotesTests) Fork_parents_are_looked_up(t *testgroup.T) {
+	ctx := context.Background()
+	parent := &github.Repo{FullName: "golang/go", CloneURL: "https://github.com/golang/go.git"}
+
+	found, err := findForkParent(ctx, nil, github.Repo{FullName: "bloomberg/cli"})
+	t.NoError(err)
+	t.Nil(found, "not a fork")
+
+	found, err = findForkParent(ctx, nil, github.Repo{Fork: true, Parent: parent})
+	t.NoError(err)
+	t.Equal(parent, found)
+
+	found, err = findForkParent(ctx, getRepoLister{repo: github.Repo{Parent: parent}},
+		github.Repo{FullName: "bloomberg/go", Fork: true})
+	t.NoError(err)
+	t.Equal(parent, found)
+
+	found, err = findForkParent(ctx, getRepoLister{err: github.ErrNotCached},
+		github.Repo{FullName: "bloomberg/go", Fork: true})
+	t.NoError(err)
+	t.Nil(found, "offline")
+
+	_, err = findForkParent(ctx, getRepoLister{err: fmt.Errorf("boom")},
+		github.Repo{FullName: "bloomberg/go", Fork: true})
+	t.Error(err)
+}
+
+func (*remotesTests) Forks_get_upstream_URLs_when_needed(t *testgroup.T) {
+	var lookups int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/orgs/bloomberg/repos", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"name": "go", "full_name": "bloomberg/go", "fork": true,
+			"clone_url": "https://ghes.example.com/bloomberg/go.git",
+			"owner": {"login": "bloomberg", "type": "Organization"}}]`)
+	})
+	mux.HandleFunc("/api/v3/repos/bloomberg/go", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&lookups, 1)
+		fmt.Fprint(w, `{"name": "go", "full_name": "bloomberg/go", "fork": true,
+			"clone_url": "https://ghes.example.com/bloomberg/go.git",
+			"owner": {"login": "bloomberg", "type": "Organization"},
+			"parent": {"full_name": "golang/go",
+				"clone_url": "https://ghes.example.com/golang/go.git",
+				"ssh_url": "git@ghes.example.com:golang/go.git"}}`)
+	})
+
+	ts := httptest.NewTLSServer(mux)
+	t.Cleanup(ts.Close)
+
+	lister := gitHubLister{ServerAndToken: github.ServerAndToken{
+		Server: strings.TrimPrefix(ts.URL, "https://"),
+		Client: ts.Client(),
+	}}
+
+	cfg := &gitHubConfig{Server: lister.Server, SingleOrg: "bloomberg"}
+	syncRoot := t.TempDir()
+	ideal, _, err := findAccountRepos(syncRoot, syncRoot, nil, cfg, lister, ioutil.Discard)
+	t.Require.NoError(err)
+
+	t.Require.Len(ideal, 1)
+	t.Equal(int32(0), atomic.LoadInt32(&lookups), "looked up before it was needed")
+
+	for _, r := range ideal {
+		upstreamURL, err := upstreamToAdd(git.LocalRepoRemotes{
+			"origin":   {FetchURL: r.URL},
+			"upstream": {FetchURL: "https://ghes.example.com/golang/go.git"},
+		}, r)
+		t.NoError(err)
+		t.Equal("", upstreamURL)
+		t.Equal(int32(0), atomic.LoadInt32(&lookups), "looked up with an upstream remote")
+
+		upstreamURL, err = upstreamToAdd(git.LocalRepoRemotes{"origin": {FetchURL: r.URL}}, r)
+		t.NoError(err)
+		t.Equal("https://ghes.example.com/golang/go.git", upstreamURL)
+		t.Equal(int32(1), atomic.LoadInt32(&lookups))
+	}
+}
+
+func (*remotesTests) Fork_parents_come_from_GraphQL_listings(t *testgroup.T) {
+	lister := gitHubListerFunc(func() []github.Repo {
+		return []github.Repo{{
+			Name:     "go",
+			FullName: "bloomberg/go",
+			Fork:     true,
+			CloneURL: "https://ghes.example.com/bloomberg/go.git",
+			Account:  github.Account{Login: "bloomberg", Type: "Organization"},
+			Parent: &github.Repo{
+				FullName: "golang/go",
+				CloneURL: "https://ghes.example.com/golang/go.git",
+			},
+		}}
+	})
+
+	cfg := &gitHubConfig{Server: "ghes.example.com", SingleOrg: "bloomberg"}
+	syncRoot := t.TempDir()
+	ideal, _, err := findAccountRepos(syncRoot, syncRoot, nil, cfg, lister, ioutil.Discard)
+	t.Require.NoError(err)
+
+	t.Require.Len(ideal, 1)
+	for _, r := range ideal {
+		t.Equal("https://ghes.example.com/golang/go.git", r.UpstreamURL)
+		t.Nil(r.findUpstreamURL)
+	}
+}
+
+// gitHubListerFunc lists the same repos for every account, and looks nothing up.
+type gitHubListerFunc func() []github.Repo
+
+func (f gitHubListerFunc) ListRepos(ctx context.Context, progress io.Writer, account github.Account,
+	repoType github.RepoType,
+) ([]github.Repo, error) {
+	return f(), nil
+}
+
+func (f gitHubListerFunc) GetAccount(ctx context.Context, name string) (github.Account, error) {
+	return github.Account{Login: name, Type: "Organization"}, nil
+}
+
+func (f gitHubListerFunc) GetRepo(ctx context.Context, fullName string) (github.Repo, error) {
+	return github.Repo{}, fmt.Errorf("unexpected lookup of %s", fullName)
+}
+
+// newFork returns a clone of a fork of a new upstream repo. The clone has no upstream remote.
+func newFork(t *testgroup.T) (upstream, fork, clone string) {
+	upstream, _ = newClone(t)
+
+	fork = filepath.Join(t.TempDir(), "fork")
+	runGit(t, filepath.Dir(fork), "clone", "--quiet", "--bare", upstream, fork)
+
+	clone = filepath.Join(t.TempDir(), "clone")
+	runGit(t, filepath.Dir(clone), "clone", "--quiet", fork, clone)
+
+	return upstream, fork, clone
+}
+
+func (*remotesTests) Sync_adds_upstream_remote(t *testgroup.T) {
+	upstream, fork, clone := newFork(t)
+
+	compURL, err := comparableRepoURL(fork)
+	t.Require.NoError(err)
+	idealRepos := idealRepoMap{compURL: {Path: clone, URL: fork, UpstreamURL: upstream}}
+
+	a, err := matchRepoToAction(clone, idealRepos, nil, nil, "origin")
+	t.Require.NoError(err)
+	t.Equal(actionSyncRepo{
+		Path:                  clone,
+		DefaultTrackingBranch: "origin/main",
+		UpstreamURL:           upstream,
+	}, a)
+
+	event := a.Do(&Options{DryRun: true})
+	t.Equal("would add upstream remote "+upstream+", and sync", event.Message)
+
+	event = a.Do(&Options{})
+	t.NotEqual(action.Failed, event.Type, event.Message)
+
+	localRepo := git.LocalRepo{Root: clone}
+	exists, err := localRepo.RefExists("refs/remotes/upstream/main")
+	t.Require.NoError(err)
+	t.True(exists, "upstream should have been fetched")
+
+	// once it's there, it's left alone
+	idealRepos = idealRepoMap{compURL: {Path: clone, URL: fork, UpstreamURL: upstream}}
+	a, err = matchRepoToAction(clone, idealRepos, nil, nil, "origin")
+	t.Require.NoError(err)
+	t.Equal(actionSyncRepo{Path: clone, DefaultTrackingBranch: "origin/main"}, a)
+}
+
+func (*remotesTests) Upstream_remote_matching_another_repo_is_tolerated(t *testgroup.T) {
+	upstream, fork, clone := newFork(t)
+	runGit(t, clone, "remote", "add", "upstream", upstream)
+
+	forkURL, err := comparableRepoURL(fork)
+	t.Require.NoError(err)
+	upstreamURL, err := comparableRepoURL(upstream)
+	t.Require.NoError(err)
+
+	// both the fork and its parent are synced
+	idealRepos := idealRepoMap{
+		forkURL:     {Path: clone, URL: fork, DefaultBranch: "main", UpstreamURL: upstream},
+		upstreamURL: {Path: "parent", URL: upstream, DefaultBranch: "main"},
+	}
+
+	a, err := matchRepoToAction(clone, idealRepos, nil, nil, "origin")
+	t.Require.NoError(err)
+	t.Equal(actionSyncRepo{Path: clone, DefaultTrackingBranch: "origin/main"}, a)
+	t.Equal(idealRepoMap{upstreamURL: {Path: "parent", URL: upstream, DefaultBranch: "main"}},
+		idealRepos, "the parent should still be cloned")
+
+	// the configured primary remote wins over other remotes
+	runGit(t, clone, "remote", "rename", "origin", "github")
+	runGit(t, clone, "remote", "add", "mirror", upstream)
+	idealRepos = idealRepoMap{
+		forkURL:     {Path: clone, URL: fork, DefaultBranch: "main"},
+		upstreamURL: {Path: "parent", URL: upstream, DefaultBranch: "main"},
+	}
+
+	a, err = matchRepoToAction(clone, copyIdealRepos(idealRepos), nil, nil, "github")
+	t.Require.NoError(err)
+	t.Equal(actionSyncRepo{Path: clone, DefaultTrackingBranch: "github/main"}, a)
+
+	_, err = matchRepoToAction(clone, copyIdealRepos(idealRepos), nil, nil, "origin")
+	t.Error(err, "mirror and github are equally good")
+}
+
+func copyIdealRepos(repos idealRepoMap) idealRepoMap {
+	c := idealRepoMap{}
+	for url, r := range repos {
+		c[url] = r
+	}
+	return c
+}
+
+func (*remotesTests) Clone_uses_remote_name_and_adds_upstream(t *testgroup.T) {
+	upstream, fork, _ := newFork(t)
+
+	dest := filepath.Join(t.TempDir(), "dest")
+	event := cloneRepo(actionCloneRepo{
+		URL:         fork,
+		Path:        dest,
+		RemoteName:  "github",
+		UpstreamURL: upstream,
+	})
+	t.Equal(action.Cloned, event.Type, event.Message)
+	t.Empty(event.Caveats)
+
+	localRepo := git.LocalRepo{Root: dest}
+	remotes, err := localRepo.Remotes()
+	t.Require.NoError(err)
+	t.Len(remotes, 2)
+	t.Equal(fork, remotes["github"].FetchURL)
+	t.Equal(upstream, remotes["upstream"].FetchURL)
+
+	exists, err := localRepo.RefExists("refs/remotes/upstream/main")
+	t.Require.NoError(err)
+	t.True(exists, "upstream should have been fetched")
+}
+
+func (*remotesTests) Clone_survives_a_missing_upstream(t *testgroup.T) {
+	_, fork, _ := newFork(t)
+
+	dest := filepath.Join(t.TempDir(), "dest")
+	event := cloneRepo(actionCloneRepo{
+		URL:         fork,
+		Path:        dest,
+		UpstreamURL: filepath.Join(t.TempDir(), "missing"),
+	})
+	t.Equal(action.Cloned, event.Type, event.Message)
+	t.Len(event.Caveats, 1)
+
+	_, err := os.Stat(filepath.Join(dest, ".git"))
+	t.NoError(err)
+}
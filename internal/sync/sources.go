@@ -0,0 +1,252 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/mikesep/frond/internal/github"
+)
+
+// sourceConfig is one place to find repos. Exactly one of its sections should be set.
+type sourceConfig struct {
+	Dir string `yaml:"dir,omitempty"` // where the source's repos go, relative to the sync root
+
+	GitHub    *gitHubConfig    `yaml:"github,omitempty"`
+	GitLab    *gitLabConfig    `yaml:"gitlab,omitempty"`
+	Gitea     *giteaConfig     `yaml:"gitea,omitempty"`
+	Bitbucket *bitbucketConfig `yaml:"bitbucket,omitempty"`
+}
+
+func (src sourceConfig) isEmpty() bool {
+	return src.Dir == "" && len(src.sections()) == 0
+}
+
+func (src sourceConfig) sections() []string {
+	var sections []string
+	if src.GitHub != nil {
+		sections = append(sections, "github")
+	}
+	if src.GitLab != nil {
+		sections = append(sections, "gitlab")
+	}
+	if src.Gitea != nil {
+		sections = append(sections, "gitea")
+	}
+	if src.Bitbucket != nil {
+		sections = append(sections, "bitbucket")
+	}
+	return sections
+}
+
+func (src sourceConfig) validate() error {
+	sections := src.sections()
+	if len(sections) > 1 {
+		return fmt.Errorf("cannot have more than one of %v", sections)
+	}
+	if len(sections) == 1 {
+		if err := src.auth().validate(sections[0]); err != nil {
+			return err
+		}
+	}
+
+	if src.Dir != "" {
+		dir := filepath.FromSlash(src.Dir)
+		if filepath.IsAbs(dir) || dir != filepath.Clean(dir) || strings.HasPrefix(dir, "..") {
+			return fmt.Errorf("dir %q should be a clean path inside the sync root", src.Dir)
+		}
+	}
+
+	switch {
+	case src.GitHub != nil:
+		return src.GitHub.validate()
+	case src.GitLab != nil:
+		return src.GitLab.validate()
+	case src.Gitea != nil:
+		return src.Gitea.validate()
+	case src.Bitbucket != nil:
+		return src.Bitbucket.validate()
+	}
+
+	return fmt.Errorf("empty config")
+}
+
+func (src sourceConfig) auth() *authConfig {
+	switch {
+	case src.GitHub != nil:
+		return src.GitHub.Auth
+	case src.GitLab != nil:
+		return src.GitLab.Auth
+	case src.Gitea != nil:
+		return src.Gitea.Auth
+	case src.Bitbucket != nil:
+		return src.Bitbucket.Auth
+	}
+	return nil
+}
+
+// String is used to say which source a repo came from.
+func (src sourceConfig) String() string {
+	var server string
+	switch {
+	case src.GitHub != nil:
+		server = src.GitHub.Server
+	case src.GitLab != nil:
+		server = src.GitLab.Server
+	case src.Gitea != nil:
+		server = src.Gitea.Server
+	case src.Bitbucket != nil:
+		server = src.Bitbucket.Server
+	}
+
+	s := strings.Join(src.sections(), "+") + " " + server
+	if src.Dir != "" {
+		s += fmt.Sprintf(" (dir %s)", src.Dir)
+	}
+	return s
+}
+
+func (src sourceConfig) findRepos(
+	sourceRoot, workDir string, cmdArgs []string, listing listingOptions, console io.Writer,
+) (idealRepoMap, rejectionReasonMap, error) {
+	if listing.offline && src.GitHub == nil {
+		return nil, nil, fmt.Errorf("%s: only github sources can be used offline", src)
+	}
+
+	switch {
+	case src.GitHub != nil:
+		return findGitHubRepos(sourceRoot, workDir, cmdArgs, src.GitHub, listing, console)
+	case src.GitLab != nil:
+		return findGitLabRepos(sourceRoot, workDir, cmdArgs, src.GitLab, listing, console)
+	case src.Gitea != nil:
+		return findGiteaRepos(sourceRoot, workDir, cmdArgs, src.Gitea, listing, console)
+	case src.Bitbucket != nil:
+		return findBitbucketRepos(sourceRoot, workDir, cmdArgs, src.Bitbucket, listing, console)
+	}
+
+	panic(fmt.Sprintf("empty source: %+v", src))
+}
+
+// listingOptions control how sources find their repos.
+type listingOptions struct {
+	syncRoot string // for finding the cache, and for relative paths in the config
+	refresh  bool   // download listings again instead of revalidating cached ones
+	offline  bool   // only use cached listings
+}
+
+func (lo listingOptions) gitHubCache(server string, auth *authConfig) *github.Cache {
+	return &github.Cache{
+		Dir:      filepath.Join(lo.syncRoot, cacheDir, "github", strings.ReplaceAll(server, ":", "_")),
+		Identity: auth.identity(),
+		Refresh:  lo.refresh,
+		Offline:  lo.offline,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// argsForSource narrows the command-line args down to the ones inside the source's root. It
+// returns nil args when the whole source should be synced, and false when none of it should.
+func argsForSource(sourceRoot, workDir string, cmdArgs []string) ([]string, bool) {
+	if len(cmdArgs) == 0 {
+		return nil, true
+	}
+
+	sep := string(filepath.Separator)
+
+	var args []string
+	for _, arg := range cmdArgs {
+		argPath := arg
+		if !filepath.IsAbs(arg) {
+			argPath = filepath.Join(workDir, arg)
+		}
+		argPath = filepath.Clean(argPath)
+
+		switch {
+		case argPath == sourceRoot || strings.HasPrefix(argPath, sourceRoot+sep):
+			args = append(args, arg)
+		case strings.HasPrefix(sourceRoot, argPath+sep):
+			return nil, true
+		}
+	}
+
+	return args, len(args) > 0
+}
+
+// sourcedRepos gathers the repos from every source and makes sure they don't step on each other.
+type sourcedRepos struct {
+	ideal    idealRepoMap
+	rejected rejectionReasonMap
+
+	sources  []string       // names, for error messages
+	sourceOf map[string]int // comparable URL -> index into sources
+}
+
+func newSourcedRepos() *sourcedRepos {
+	return &sourcedRepos{
+		ideal:    idealRepoMap{},
+		rejected: rejectionReasonMap{},
+		sourceOf: map[string]int{},
+	}
+}
+
+func (sr *sourcedRepos) add(src string, ideal idealRepoMap, rejected rejectionReasonMap,
+) error {
+	sr.sources = append(sr.sources, src)
+	srcIndex := len(sr.sources) - 1
+
+	for url, repo := range ideal {
+		if other, ok := sr.sourceOf[url]; ok {
+			return fmt.Errorf("%s is in both %s and %s", url, sr.sources[other], src)
+		}
+		sr.ideal[url] = repo
+		sr.sourceOf[url] = srcIndex
+
+		delete(sr.rejected, url) // one source keeping a repo wins over another rejecting it
+	}
+
+	for url, reason := range rejected {
+		if _, ok := sr.ideal[url]; !ok {
+			sr.rejected[url] = reason
+		}
+	}
+
+	return nil
+}
+
+// checkPathCollisions makes sure that no repo from one source would be cloned at, or inside, a
+// repo from another source.
+func (sr *sourcedRepos) checkPathCollisions() error {
+	urlAtPath := map[string]string{}
+	for url, repo := range sr.ideal {
+		path := filepath.Clean(repo.Path)
+		if other, ok := urlAtPath[path]; ok && sr.sourceOf[other] != sr.sourceOf[url] {
+			return sr.collisionError(other, url, path)
+		}
+		urlAtPath[path] = url
+	}
+
+	for path, url := range urlAtPath {
+		for dir := filepath.Dir(path); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+			if other, ok := urlAtPath[dir]; ok && sr.sourceOf[other] != sr.sourceOf[url] {
+				return sr.collisionError(other, url, path)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (sr *sourcedRepos) collisionError(urlA, urlB, path string) error {
+	// sort for a stable message
+	if urlA > urlB {
+		urlA, urlB = urlB, urlA
+	}
+
+	return fmt.Errorf("%s from %s and %s from %s collide at %s",
+		urlA, sr.sources[sr.sourceOf[urlA]], urlB, sr.sources[sr.sourceOf[urlB]], path)
+}
@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/bloomberg/go-testgroup"
+	"github.com/mikesep/frond/internal/github"
+)
+
+func Test_Sources(t *testing.T) {
+	testgroup.RunInParallel(t, &sourcesTests{})
+}
+
+type sourcesTests struct{}
+
+func (*sourcesTests) Args_are_narrowed_to_each_source(t *testgroup.T) {
+	root := filepath.FromSlash("/sync")
+	ghRoot := filepath.Join(root, "github.com")
+
+	args, ok := argsForSource(ghRoot, root, nil)
+	t.True(ok)
+	t.Nil(args)
+
+	args, ok = argsForSource(ghRoot, root, []string{"github.com/bloomberg", "gitlab.com"})
+	t.True(ok)
+	t.Equal([]string{"github.com/bloomberg"}, args)
+
+	_, ok = argsForSource(ghRoot, root, []string{"gitlab.com"})
+	t.False(ok)
+
+	args, ok = argsForSource(ghRoot, ghRoot, []string{"."})
+	t.True(ok)
+	t.Equal([]string{"."}, args)
+
+	args, ok = argsForSource(ghRoot, filepath.Join(ghRoot, "bloomberg"), []string{"../.."})
+	t.True(ok)
+	t.Nil(args)
+
+	args, ok = argsForSource(ghRoot, root, []string{"github.com-other"})
+	t.False(ok)
+	t.Nil(args)
+}
+
+func (*sourcesTests) Repos_from_different_sources_are_merged(t *testgroup.T) {
+	gh := sourceConfig{Dir: "gh", GitHub: &gitHubConfig{Server: "github.com"}}
+	gl := sourceConfig{Dir: "gl", GitLab: &gitLabConfig{Server: "gitlab.com"}}
+
+	repos := newSourcedRepos()
+	t.NoError(repos.add(gh.String(),
+		idealRepoMap{"github.com/a/b": {Path: filepath.Join("gh", "a", "b")}},
+		rejectionReasonMap{"github.com/a/old": "repo is archived"}))
+	t.NoError(repos.add(gl.String(),
+		idealRepoMap{"gitlab.com/a/b": {Path: filepath.Join("gl", "a", "b")}},
+		nil))
+
+	t.Len(repos.ideal, 2)
+	t.Equal(rejectionReasonMap{"github.com/a/old": "repo is archived"}, repos.rejected)
+	t.NoError(repos.checkPathCollisions())
+}
+
+func (*sourcesTests) Same_repo_in_two_sources_is_an_error(t *testgroup.T) {
+	ghA := sourceConfig{Dir: "a", GitHub: &gitHubConfig{Server: "github.com"}}
+	ghB := sourceConfig{Dir: "b", GitHub: &gitHubConfig{Server: "github.com"}}
+
+	repos := newSourcedRepos()
+	t.NoError(repos.add(ghA.String(), idealRepoMap{"github.com/a/b": {Path: "a/b"}}, nil))
+	t.EqualError(repos.add(ghB.String(), idealRepoMap{"github.com/a/b": {Path: "b/b"}}, nil),
+		"github.com/a/b is in both github github.com (dir a) and github github.com (dir b)")
+}
+
+func (*sourcesTests) Kept_repos_win_over_rejected_ones(t *testgroup.T) {
+	ghA := sourceConfig{GitHub: &gitHubConfig{Server: "github.com"}}
+	ghB := sourceConfig{GitHub: &gitHubConfig{Server: "github.com"}}
+
+	repos := newSourcedRepos()
+	t.NoError(repos.add(ghA.String(), nil, rejectionReasonMap{"github.com/a/b": "nope"}))
+	t.NoError(repos.add(ghB.String(), idealRepoMap{"github.com/a/b": {Path: "b"}}, nil))
+	t.NoError(repos.add(ghA.String(), nil, rejectionReasonMap{"github.com/a/b": "still nope"}))
+
+	t.Len(repos.ideal, 1)
+	t.Empty(repos.rejected)
+}
+
+func (*sourcesTests) Paths_from_different_sources_cannot_collide(t *testgroup.T) {
+	gh := sourceConfig{GitHub: &gitHubConfig{Server: "github.com"}}
+	gl := sourceConfig{GitLab: &gitLabConfig{Server: "gitlab.com"}}
+
+	same := newSourcedRepos()
+	t.NoError(same.add(gh.String(), idealRepoMap{"github.com/a/tools": {Path: "tools"}}, nil))
+	t.NoError(same.add(gl.String(), idealRepoMap{"gitlab.com/b/tools": {Path: "tools"}}, nil))
+	t.EqualError(same.checkPathCollisions(),
+		"github.com/a/tools from github github.com and gitlab.com/b/tools from gitlab gitlab.com"+
+			" collide at tools")
+
+	nested := newSourcedRepos()
+	t.NoError(nested.add(gh.String(), idealRepoMap{"github.com/a/tools": {Path: "tools"}}, nil))
+	t.NoError(nested.add(gl.String(), idealRepoMap{
+		"gitlab.com/b/tools-cli": {Path: "tools-cli"},
+		"gitlab.com/b/tools/x":   {Path: filepath.Join("tools", "x")},
+	}, nil))
+	t.Error(nested.checkPathCollisions())
+
+	sibling := newSourcedRepos()
+	t.NoError(sibling.add(gh.String(), idealRepoMap{"github.com/a/tools": {Path: "tools"}}, nil))
+	t.NoError(sibling.add(gl.String(), idealRepoMap{"gitlab.com/b/tools-cli": {Path: "tools-cli"}}, nil))
+	t.NoError(sibling.checkPathCollisions())
+}
+
+func (*sourcesTests) GitHub_caches_are_per_server(t *testgroup.T) {
+	listing := listingOptions{syncRoot: filepath.FromSlash("/sync"), offline: true}
+
+	cache := listing.gitHubCache("ghes.example.com:8443", nil)
+	t.Equal(filepath.FromSlash("/sync/.frond/cache/github/ghes.example.com_8443"), cache.Dir)
+	t.True(cache.Offline)
+	t.False(cache.Refresh)
+
+	app := listing.gitHubCache("ghes.example.com:8443", &authConfig{Type: "app", AppID: 1})
+	t.Equal(cache.Dir, app.Dir)
+	t.NotEqual(cache.Identity, app.Identity)
+}
+
+func (*sourcesTests) Only_GitHub_sources_work_offline(t *testgroup.T) {
+	gl := sourceConfig{GitLab: &gitLabConfig{Server: "gitlab.com"}}
+
+	root := t.TempDir()
+	_, _, err := gl.findRepos(root, root, nil, listingOptions{offline: true}, ioutil.Discard)
+	t.EqualError(err, "gitlab gitlab.com: only github sources can be used offline")
+}
+
+func (*sourcesTests) Offline_GitHub_sources_need_no_token(t *testgroup.T) {
+	gh := sourceConfig{GitHub: &gitHubConfig{
+		Server:    "github.com",
+		SingleOrg: "bloomberg",
+		Auth:      &authConfig{Type: "file", File: "missing-token"},
+	}}
+
+	root := t.TempDir()
+	listing := listingOptions{syncRoot: root, offline: true}
+	_, _, err := gh.findRepos(root, root, nil, listing, ioutil.Discard)
+	t.True(errors.Is(err, github.ErrNotCached), "%v", err)
+}
@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// staticRepoConfig is a repo that's listed in the config instead of being found with an API.
+type staticRepoConfig struct {
+	URL    string `yaml:"url"`
+	Path   string `yaml:"path"`             // relative to the sync root
+	Branch string `yaml:"branch,omitempty"` // default: the remote's HEAD
+}
+
+func validateStaticRepos(repos []staticRepoConfig) error {
+	urls := map[string]bool{}
+	paths := map[string]bool{}
+
+	for i, r := range repos {
+		if r.URL == "" {
+			return fmt.Errorf("repos[%d]: url is missing", i)
+		}
+		compURL, err := comparableRepoURL(r.URL)
+		if err != nil {
+			return fmt.Errorf("repos[%d]: bad url %q: %w", i, r.URL, err)
+		}
+		if urls[compURL] {
+			return fmt.Errorf("repos[%d]: %s is listed more than once", i, r.URL)
+		}
+		urls[compURL] = true
+
+		if r.Path == "" {
+			return fmt.Errorf("repos[%d]: path is missing", i)
+		}
+		path := filepath.FromSlash(r.Path)
+		if filepath.IsAbs(path) || path != filepath.Clean(path) || path == "." ||
+			strings.HasPrefix(path, "..") {
+			return fmt.Errorf("repos[%d]: path %q should be a clean path inside the sync root",
+				i, r.Path)
+		}
+		if paths[path] {
+			return fmt.Errorf("repos[%d]: path %s is used more than once", i, r.Path)
+		}
+		paths[path] = true
+	}
+
+	return nil
+}
+
+func findStaticRepos(syncRoot, workDir string, cmdArgs []string, repos []staticRepoConfig,
+) (idealRepoMap, error) {
+	absTargets, err := absTargetsInsideRoot(syncRoot, workDir, cmdArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	idealRepos := idealRepoMap{}
+
+	for _, r := range repos {
+		compURL, err := comparableRepoURL(r.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		absPath := filepath.Join(syncRoot, filepath.FromSlash(r.Path))
+		if !isUnderAnyTarget(absPath, absTargets) {
+			continue
+		}
+
+		pathToRepo, err := filepath.Rel(workDir, absPath)
+		if err != nil {
+			return nil, err
+		}
+
+		idealRepos[compURL] = idealRepo{
+			Path:          pathToRepo,
+			URL:           r.URL,
+			DefaultBranch: r.Branch,
+			CloneBranch:   r.Branch,
+		}
+	}
+
+	return idealRepos, nil
+}
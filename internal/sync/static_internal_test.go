@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bloomberg/go-testgroup"
+	"github.com/mikesep/frond/internal/action"
+	"github.com/mikesep/frond/internal/git"
+)
+
+func Test_StaticRepos(t *testing.T) {
+	testgroup.RunInParallel(t, &staticReposTests{})
+}
+
+type staticReposTests struct{}
+
+func (*staticReposTests) Decode_repos(t *testgroup.T) {
+	cfg, err := parseConfig(strings.NewReader(`
+repos:
+- url: https://github.com/golang/go.git
+  path: upstream/go
+  branch: release-branch.go1.17
+- url: git@mirror.example.com:tools/cli.git
+  path: mirrors/cli
+`))
+	t.Require.NoError(err)
+
+	t.Empty(cfg.sources())
+	t.Equal([]staticRepoConfig{
+		{URL: "https://github.com/golang/go.git", Path: "upstream/go", Branch: "release-branch.go1.17"},
+		{URL: "git@mirror.example.com:tools/cli.git", Path: "mirrors/cli"},
+	}, cfg.Repos)
+}
+
+func (*staticReposTests) Reject_bad_repos(t *testgroup.T) {
+	for _, repos := range [][]staticRepoConfig{
+		{{Path: "x"}},
+		{{URL: "https://example.com/a/x.git"}},
+		{{URL: "https://example.com/a/x.git", Path: "../x"}},
+		{{URL: "https://example.com/a/x.git", Path: "/x"}},
+		{
+			{URL: "https://example.com/a/x.git", Path: "x"},
+			{URL: "git@example.com:a/x.git", Path: "y"},
+		},
+		{
+			{URL: "https://example.com/a/x.git", Path: "x"},
+			{URL: "https://example.com/a/y.git", Path: "x"},
+		},
+	} {
+		t.Error(validateStaticRepos(repos), "%+v", repos)
+	}
+}
+
+func (*staticReposTests) Repos_are_narrowed_by_args(t *testgroup.T) {
+	root := t.TempDir()
+	repos := []staticRepoConfig{
+		{URL: "https://github.com/golang/go.git", Path: "upstream/go", Branch: "master"},
+		{URL: "git@mirror.example.com:tools/cli.git", Path: "mirrors/cli"},
+	}
+
+	ideal, err := findStaticRepos(root, root, nil, repos)
+	t.Require.NoError(err)
+	t.Equal(idealRepoMap{
+		"github.com/golang/go": {
+			Path:          filepath.Join("upstream", "go"),
+			URL:           "https://github.com/golang/go.git",
+			DefaultBranch: "master",
+			CloneBranch:   "master",
+		},
+		"mirror.example.com/tools/cli": {
+			Path: filepath.Join("mirrors", "cli"),
+			URL:  "git@mirror.example.com:tools/cli.git",
+		},
+	}, ideal)
+
+	ideal, err = findStaticRepos(root, filepath.Join(root, "mirrors"), []string{"."}, repos)
+	t.Require.NoError(err)
+	t.Equal(idealRepoMap{
+		"mirror.example.com/tools/cli": {
+			Path: "cli",
+			URL:  "git@mirror.example.com:tools/cli.git",
+		},
+	}, ideal)
+}
+
+func (*staticReposTests) Clone_checks_out_the_branch(t *testgroup.T) {
+	upstream, _ := newClone(t)
+	runGit(t, upstream, "branch", "stable")
+
+	dest := filepath.Join(t.TempDir(), "dest")
+	event := cloneRepo(actionCloneRepo{URL: upstream, Path: dest, Branch: "stable"})
+	t.Equal(action.Cloned, event.Type, event.Message)
+
+	repo := git.LocalRepo{Root: dest}
+	branch, err := repo.CurrentBranch()
+	t.Require.NoError(err)
+	t.Equal("stable", branch)
+}
+
+func (*staticReposTests) Sync_without_branch_uses_remote_HEAD(t *testgroup.T) {
+	upstream, clone := newClone(t)
+
+	compURL, err := comparableRepoURL(upstream)
+	t.Require.NoError(err)
+
+	a, err := matchRepoToAction(clone, idealRepoMap{compURL: {Path: clone, URL: upstream}}, nil, nil,
+		"origin")
+	t.Require.NoError(err)
+	t.Equal(actionSyncRepo{Path: clone, DefaultTrackingBranch: "origin/main"}, a)
+}
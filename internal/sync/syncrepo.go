@@ -8,32 +8,53 @@ import (
 	"os/exec"
 	"strings"
 
+	"github.com/mikesep/frond/internal/action"
 	"github.com/mikesep/frond/internal/git"
 )
 
-func cloneRepo(url, path string) actionEvent {
-	cmd := exec.Command("git", "clone", url, path)
+func cloneRepo(a actionCloneRepo) action.Event {
+	args := []string{"clone"}
+	if a.Branch != "" {
+		args = append(args, "--branch", a.Branch)
+	}
+	if a.RemoteName != "" {
+		args = append(args, "--origin", a.RemoteName)
+	}
+	args = append(args, a.URL, a.Path)
+
+	cmd := exec.Command("git", args...)
 	_, err := cmd.CombinedOutput()
 
 	if err != nil {
-		return actionEvent{
-			Type:    actionFailed,
-			Name:    path,
+		return action.Event{
+			Type:    action.Failed,
+			Name:    a.Path,
 			Message: err.Error(),
 		}
 	}
 
-	return actionEvent{
-		Type:    actionCloned,
-		Name:    path,
-		Message: fmt.Sprintf("cloned from %s", url),
+	// The clone is usable without its upstream remote, so problems with it are only caveats.
+	var caveats []string
+	if a.UpstreamURL != "" {
+		if err := addUpstreamRemote(a.Path, a.UpstreamURL); err != nil {
+			caveats = append(caveats, err.Error())
+		} else if _, err := (&git.LocalRepo{Root: a.Path}).FetchAllAndPrune(); err != nil {
+			caveats = append(caveats, fmt.Sprintf("failed to fetch %s: %v", upstreamRemoteName, err))
+		}
+	}
+
+	return action.Event{
+		Type:    action.Cloned,
+		Name:    a.Path,
+		Message: fmt.Sprintf("cloned from %s", a.URL),
+		Caveats: caveats,
 	}
 }
 
-func syncRepo(repoPath, defaultTrackingBranch string) actionEvent {
-	failure := func(err error) actionEvent {
-		return actionEvent{
-			Type:    actionFailed,
+func syncRepo(repoPath, defaultTrackingBranch string, reset bool) action.Event {
+	failure := func(err error) action.Event {
+		return action.Event{
+			Type:    action.Failed,
 			Name:    repoPath,
 			Message: err.Error(),
 		}
@@ -51,10 +72,13 @@ func syncRepo(repoPath, defaultTrackingBranch string) actionEvent {
 		return failure(err)
 	}
 
-	// TODO option to force branches to match tracking branches?
+	if reset {
+		return resetRepo(repo, origBranches, defaultTrackingBranch, updated)
+	}
+
 	if !updated {
-		return actionEvent{
-			Type:    actionUnchanged,
+		return action.Event{
+			Type:    action.Unchanged,
 			Name:    repoPath,
 			Message: "no updates",
 		}
@@ -68,22 +92,8 @@ func syncRepo(repoPath, defaultTrackingBranch string) actionEvent {
 	if origBranches[currentBranch].UpstreamTrack == "" && // (empty = in sync)
 		newBranches[currentBranch].UpstreamTrack == "gone" {
 
-		var branchTrackingRemoteDefault string
-		for branchName, branchInfo := range newBranches {
-			if branchInfo.UpstreamBranch == defaultTrackingBranch {
-				branchTrackingRemoteDefault = branchName
-				break
-			}
-		}
-
-		if branchTrackingRemoteDefault != "" {
-			if err := repo.SwitchToExistingBranch(branchTrackingRemoteDefault); err != nil {
-				return failure(err)
-			}
-		} else {
-			if err := repo.SwitchToNewTrackingBranch(defaultTrackingBranch); err != nil {
-				return failure(err)
-			}
+		if err := switchToDefaultBranch(repo, newBranches, defaultTrackingBranch); err != nil {
+			return failure(err)
 		}
 
 		currentBranch, err = repo.CurrentBranch()
@@ -129,10 +139,164 @@ func syncRepo(repoPath, defaultTrackingBranch string) actionEvent {
 		}
 	}
 
-	return actionEvent{
-		Type:    actionUpdated,
+	return action.Event{
+		Type:    action.Updated,
 		Name:    repoPath,
 		Message: "updated",
 		Caveats: caveats,
 	}
 }
+
+// resetRepo gets a freshly fetched repo as close to pristine as it can without losing work:
+// it switches to the branch tracking defaultTrackingBranch and moves every branch whose commits
+// have all been pushed somewhere to its upstream. Like syncRepo, it only deletes branches whose
+// upstream was deleted by this fetch. Repos with uncommitted changes, or with untracked files
+// that the reset would overwrite, are left alone.
+func resetRepo(repo git.LocalRepo, origBranches git.LocalRepoBranches,
+	defaultTrackingBranch string, fetchedUpdates bool,
+) action.Event {
+	failure := func(err error) action.Event {
+		return action.Event{
+			Type:    action.Failed,
+			Name:    repo.Root,
+			Message: err.Error(),
+		}
+	}
+
+	status, err := git.GetStatus(repo.Root)
+	if err != nil {
+		return failure(err)
+	}
+	if status.ChangedOrRenamed || status.Unmerged {
+		return action.Event{
+			Type:    action.Ignored,
+			Name:    repo.Root,
+			Message: "refused to reset since it has uncommitted changes",
+		}
+	}
+	if status.Untracked {
+		colliding, err := repo.UntrackedFilesIn(defaultTrackingBranch)
+		if err != nil {
+			return failure(err)
+		}
+		if len(colliding) > 0 {
+			return action.Event{
+				Type: action.Ignored,
+				Name: repo.Root,
+				Message: fmt.Sprintf("refused to reset since it would overwrite untracked files: %s",
+					strings.Join(colliding, ", ")),
+			}
+		}
+	}
+
+	changed := fetchedUpdates
+	var caveats []string
+
+	branches, currentBranch, err := repo.LocalBranches()
+	if err != nil {
+		return failure(err)
+	}
+
+	if branches[currentBranch].UpstreamBranch != defaultTrackingBranch {
+		if err := switchToDefaultBranch(repo, branches, defaultTrackingBranch); err != nil {
+			return failure(err)
+		}
+		changed = true
+
+		branches, currentBranch, err = repo.LocalBranches()
+		if err != nil {
+			return failure(err)
+		}
+	}
+
+	for branch, info := range branches {
+		if info.UpstreamTrack == "" {
+			// in sync with upstream (or no upstream at all)
+			continue
+		}
+
+		unpushed, err := repo.HasUnpushedCommits(branch)
+		if err != nil {
+			return failure(err)
+		}
+		if unpushed {
+			caveats = append(caveats,
+				fmt.Sprintf("left %q in place since it had unpushed changes", branch))
+			continue
+		}
+
+		switch {
+		case info.UpstreamTrack == "gone":
+			if origBranches[branch].UpstreamTrack != "" { // wasn't in sync before
+				caveats = append(caveats,
+					fmt.Sprintf("left %q in place since its upstream is gone", branch))
+				continue
+			}
+			const force = true
+			if err := repo.DeleteBranch(branch, force); err != nil {
+				return failure(err)
+			}
+			caveats = append(caveats, fmt.Sprintf("deleted %q", branch))
+
+		case branch == currentBranch:
+			if err := repo.KeepReset(info.UpstreamBranch); err != nil {
+				return failure(err)
+			}
+			if !strings.HasPrefix(info.UpstreamTrack, "behind") {
+				caveats = append(caveats, fmt.Sprintf("reset %q to %q", branch, info.UpstreamBranch))
+			}
+
+		default:
+			if err := repo.ResetBranch(branch, info.UpstreamBranch); err != nil {
+				return failure(err)
+			}
+			if !strings.HasPrefix(info.UpstreamTrack, "behind") {
+				caveats = append(caveats, fmt.Sprintf("reset %q to %q", branch, info.UpstreamBranch))
+			}
+		}
+		changed = true
+	}
+
+	if !changed {
+		return action.Event{
+			Type:    action.Unchanged,
+			Name:    repo.Root,
+			Message: "no updates",
+		}
+	}
+
+	return action.Event{
+		Type:    action.Updated,
+		Name:    repo.Root,
+		Message: fmt.Sprintf("reset to %s", defaultTrackingBranch),
+		Caveats: caveats,
+	}
+}
+
+// switchToDefaultBranch prefers a local branch with the same name as the default branch, then
+// any other branch tracking it, before creating a new tracking branch.
+func switchToDefaultBranch(
+	repo git.LocalRepo, branches git.LocalRepoBranches, defaultTrackingBranch string,
+) error {
+	defaultBranchName := defaultTrackingBranch[strings.Index(defaultTrackingBranch, "/")+1:]
+
+	var branchTrackingRemoteDefault string
+	for branchName, branchInfo := range branches {
+		if branchInfo.UpstreamBranch != defaultTrackingBranch {
+			continue
+		}
+		if branchName == defaultBranchName {
+			branchTrackingRemoteDefault = branchName
+			break
+		}
+		if branchTrackingRemoteDefault == "" || branchName < branchTrackingRemoteDefault {
+			branchTrackingRemoteDefault = branchName
+		}
+	}
+
+	if branchTrackingRemoteDefault != "" {
+		return repo.SwitchToExistingBranch(branchTrackingRemoteDefault)
+	}
+
+	return repo.SwitchToNewTrackingBranch(defaultTrackingBranch)
+}
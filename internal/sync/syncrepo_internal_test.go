@@ -0,0 +1,169 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package sync
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bloomberg/go-testgroup"
+	"github.com/mikesep/frond/internal/action"
+	"github.com/mikesep/frond/internal/git"
+)
+
+func Test_resetRepo(t *testing.T) {
+	testgroup.RunInParallel(t, &resetRepoTests{})
+}
+
+type resetRepoTests struct{}
+
+// newClone returns the path to a clone of a new upstream repo with a commit on main.
+func newClone(t *testgroup.T) (upstream, clone string) {
+	root := t.TempDir()
+	upstream = filepath.Join(root, "upstream")
+	clone = filepath.Join(root, "clone")
+
+	t.Require.NoError(os.MkdirAll(upstream, 0o755))
+	runGit(t, upstream, "init", "--quiet", "--initial-branch=main")
+	commitEmpty(t, upstream, "one")
+	runGit(t, root, "clone", "--quiet", upstream, clone)
+
+	return upstream, clone
+}
+
+func (*resetRepoTests) Switches_to_default_and_resets_pushed_branches(t *testgroup.T) {
+	upstream, clone := newClone(t)
+
+	// "pushed" diverged from the upstream's branch of the same name, but its commits are on
+	// the upstream's "main" (think: squash-merged and force-pushed)
+	runGit(t, upstream, "branch", "pushed")
+	runGit(t, clone, "fetch", "--quiet")
+	runGit(t, clone, "switch", "--quiet", "--track", "origin/pushed")
+	commitEmpty(t, upstream, "two")
+	runGit(t, clone, "fetch", "--quiet")
+	runGit(t, clone, "reset", "--quiet", "--hard", "origin/main")
+	runGit(t, upstream, "switch", "--quiet", "pushed")
+	commitEmpty(t, upstream, "three")
+	runGit(t, upstream, "switch", "--quiet", "main")
+	runGit(t, clone, "fetch", "--quiet")
+
+	// "unpushed" has a local commit
+	runGit(t, clone, "switch", "--quiet", "--create", "unpushed", "--track", "origin/main")
+	commitEmpty(t, clone, "local work")
+	runGit(t, clone, "switch", "--quiet", "pushed")
+
+	repo := git.LocalRepo{Root: clone}
+
+	event := resetRepo(repo, localBranches(t, repo), "origin/main", false)
+	t.Equal(action.Updated, event.Type, event.Message)
+	t.Contains(event.Caveats, `reset "pushed" to "origin/pushed"`)
+	t.Contains(event.Caveats, `left "unpushed" in place since it had unpushed changes`)
+
+	branches, current, err := repo.LocalBranches()
+	t.Require.NoError(err)
+	t.Equal("main", current)
+	t.Equal("", branches["main"].UpstreamTrack)
+	t.Equal("", branches["pushed"].UpstreamTrack)
+	t.Equal("ahead 1", branches["unpushed"].UpstreamTrack)
+}
+
+func (*resetRepoTests) Refuses_with_uncommitted_changes(t *testgroup.T) {
+	_, clone := newClone(t)
+
+	runGit(t, clone, "switch", "--quiet", "--create", "feature")
+	t.Require.NoError(os.WriteFile(filepath.Join(clone, "file"), []byte("x\n"), 0o644))
+	runGit(t, clone, "add", "file")
+
+	repo := git.LocalRepo{Root: clone}
+
+	event := resetRepo(repo, localBranches(t, repo), "origin/main", false)
+	t.Equal(action.Ignored, event.Type)
+	t.Contains(event.Message, "uncommitted changes")
+
+	current, err := repo.CurrentBranch()
+	t.Require.NoError(err)
+	t.Equal("feature", current)
+}
+
+func (*resetRepoTests) Unchanged_when_pristine(t *testgroup.T) {
+	_, clone := newClone(t)
+
+	repo := git.LocalRepo{Root: clone}
+
+	event := resetRepo(repo, localBranches(t, repo), "origin/main", false)
+	t.Equal(action.Unchanged, event.Type, event.Message)
+}
+
+func (*resetRepoTests) Only_deletes_branches_gone_since_the_last_fetch(t *testgroup.T) {
+	upstream, clone := newClone(t)
+
+	runGit(t, upstream, "branch", "stale")
+	runGit(t, upstream, "branch", "merged")
+	runGit(t, clone, "fetch", "--quiet")
+	runGit(t, clone, "branch", "--quiet", "--track", "stale", "origin/stale")
+	runGit(t, clone, "branch", "--quiet", "--track", "merged", "origin/merged")
+
+	// "stale" was already gone before this sync's fetch
+	runGit(t, upstream, "branch", "--delete", "stale")
+	runGit(t, clone, "fetch", "--quiet", "--prune")
+
+	repo := git.LocalRepo{Root: clone}
+	origBranches := localBranches(t, repo)
+
+	runGit(t, upstream, "branch", "--delete", "merged")
+	runGit(t, clone, "fetch", "--quiet", "--prune")
+
+	event := resetRepo(repo, origBranches, "origin/main", true)
+	t.Equal(action.Updated, event.Type, event.Message)
+	t.Contains(event.Caveats, `deleted "merged"`)
+	t.Contains(event.Caveats, `left "stale" in place since its upstream is gone`)
+
+	branches := localBranches(t, repo)
+	t.NotContains(branches, "merged")
+	t.Contains(branches, "stale")
+}
+
+func (*resetRepoTests) Refuses_to_overwrite_untracked_files(t *testgroup.T) {
+	upstream, clone := newClone(t)
+
+	runGit(t, clone, "switch", "--quiet", "--create", "feature")
+	t.Require.NoError(os.WriteFile(filepath.Join(upstream, "file"), []byte("theirs\n"), 0o644))
+	runGit(t, upstream, "add", "file")
+	commitEmpty(t, upstream, "add file")
+	runGit(t, clone, "fetch", "--quiet")
+	t.Require.NoError(os.WriteFile(filepath.Join(clone, "file"), []byte("mine\n"), 0o644))
+
+	repo := git.LocalRepo{Root: clone}
+
+	event := resetRepo(repo, localBranches(t, repo), "origin/main", true)
+	t.Equal(action.Ignored, event.Type)
+	t.Equal("refused to reset since it would overwrite untracked files: file", event.Message)
+
+	contents, err := os.ReadFile(filepath.Join(clone, "file"))
+	t.Require.NoError(err)
+	t.Equal("mine\n", string(contents))
+}
+
+func localBranches(t *testgroup.T, repo git.LocalRepo) git.LocalRepoBranches {
+	branches, _, err := repo.LocalBranches()
+	t.Require.NoError(err)
+	return branches
+}
+
+//------------------------------------------------------------------------------
+
+func runGit(t *testgroup.T, dir string, args ...string) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	t.Require.NoError(err, "git %s: %s", strings.Join(args, " "), out)
+}
+
+func commitEmpty(t *testgroup.T, dir, message string) {
+	runGit(t, dir, "-c", "user.name=frond", "-c", "user.email=frond@example.com",
+		"commit", "--quiet", "--allow-empty", "--message="+message)
+}
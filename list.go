@@ -5,61 +5,30 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/mikesep/frond/internal/git"
 )
 
 type listOptions struct {
+	repoSelectionOptions
 }
 
 func (opts *listOptions) Execute(args []string) error {
-	// repos, err := listRepos(args)
-	repos, err := git.FindReposInDir(".")
+	repos, err := opts.selectRepos(args)
 	if err != nil {
 		return err
 	}
 
-	if len(repos) == 0 {
-		return nil
+	for _, r := range repos {
+		fmt.Println(r)
 	}
 
-	// TODO reimplement
-	fmt.Printf("Found %d repos.\n", len(repos))
-	// maxRepoLen := maxLength(repos)
-
-	// for _, r := range repos {
-	// 	st, err := getGitStatus(r)
-	// 	if err != nil {
-	// 		return err
-	// 	}
-
-	// 	if st.ChangedOrRenamed {
-	// 		fmt.Printf("c")
-	// 	} else {
-	// 		fmt.Printf(" ")
-	// 	}
-
-	// 	if st.Unmerged {
-	// 		fmt.Printf("U")
-	// 	} else {
-	// 		fmt.Printf(" ")
-	// 	}
-
-	// 	if st.Untracked {
-	// 		fmt.Printf("?")
-	// 	} else {
-	// 		fmt.Printf(" ")
-	// 	}
-
-	// 	if st.Ignored {
-	// 		fmt.Printf("i")
-	// 	} else {
-	// 		fmt.Printf(" ")
-	// 	}
-
-	// 	fmt.Printf(" %s%s %s\n", r, strings.Repeat(" ", maxRepoLen-len(r)), st.BranchHead)
-	// }
-
 	return nil
 }
 
@@ -73,6 +42,174 @@ func maxLength(strs []string) int {
 	return max
 }
 
+//------------------------------------------------------------------------------
+
+const regexpPrefix = "re:"
+
+// repoSelectionOptions are shared by commands that operate on the repos found under the
+// positional path args. The status filters are OR'd together; --branch is AND'd with them.
+type repoSelectionOptions struct {
+	Changed   bool   `long:"changed" description:"Select repos with changed or renamed files."`
+	Stashed   bool   `long:"stashed" description:"Select repos with stashed changes."`
+	Untracked bool   `long:"untracked" description:"Select repos with untracked files."`
+	Unmerged  bool   `long:"unmerged" description:"Select repos with unmerged files."`
+	Ignored   bool   `long:"ignored" description:"Select repos with ignored files."`
+	Branch    string `long:"branch" value-name:"PATTERN" description:"Select repos whose current branch matches a glob (or a regexp prefixed with re:)."`
+}
+
+// selectRepos returns the repos found under the given paths (or the current directory),
+// relative to the current directory, sorted, and narrowed down by the filters.
+func (opts *repoSelectionOptions) selectRepos(args []string) ([]string, error) {
+	matchBranch, err := newBranchMatcher(opts.Branch)
+	if err != nil {
+		return nil, err
+	}
+
+	repos, err := findRelativeRepos(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.anyStatusFilter() && matchBranch == nil {
+		return repos, nil
+	}
+
+	var selected []string
+
+	for _, r := range repos {
+		st, err := git.GetStatus(r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", r, err)
+		}
+
+		if opts.anyStatusFilter() && !opts.matchesStatus(st) {
+			continue
+		}
+		if matchBranch != nil && !matchBranch(st.BranchHead) {
+			continue
+		}
+
+		selected = append(selected, r)
+	}
+
+	return selected, nil
+}
+
+func (opts *repoSelectionOptions) anyStatusFilter() bool {
+	return opts.Changed || opts.Stashed || opts.Untracked || opts.Unmerged || opts.Ignored
+}
+
+func (opts *repoSelectionOptions) matchesStatus(st git.Status) bool {
+	return (opts.Changed && st.ChangedOrRenamed) ||
+		(opts.Stashed && st.Stashed) ||
+		(opts.Untracked && st.Untracked) ||
+		(opts.Unmerged && st.Unmerged) ||
+		(opts.Ignored && st.Ignored)
+}
+
+// newBranchMatcher returns nil when pattern is empty.
+func newBranchMatcher(pattern string) (func(branch string) bool, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(pattern, regexpPrefix) {
+		re, err := regexp.Compile(strings.TrimPrefix(pattern, regexpPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("bad --branch regexp %q: %w", pattern, err)
+		}
+		return re.MatchString, nil
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("bad --branch glob %q: %w", pattern, err)
+	}
+	return func(branch string) bool {
+		matched, _ := path.Match(pattern, branch) // pattern was already checked
+		return matched
+	}, nil
+}
+
+func findRelativeRepos(args []string) ([]string, error) {
+	workDir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+
+	args, err = expandPathGlobs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// De-duplicate repos because args could overlap
+	repoSet := map[string]bool{}
+
+	for _, arg := range args {
+		abs, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		repos, err := git.FindReposInDir(abs)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range repos {
+			rel, err := filepath.Rel(workDir, r)
+			if err != nil {
+				return nil, err
+			}
+			repoSet[rel] = true
+		}
+	}
+
+	repos := make([]string, 0, len(repoSet))
+	for r := range repoSet {
+		repos = append(repos, r)
+	}
+	sort.Strings(repos)
+
+	return repos, nil
+}
+
+// expandPathGlobs expands args like "org*" that the shell left alone, because they were quoted
+// or didn't match anything there, into the directories they match. Args that exist as paths
+// are left alone, even if they look like globs.
+func expandPathGlobs(args []string) ([]string, error) {
+	var expanded []string
+
+	for _, arg := range args {
+		if _, err := os.Stat(arg); err == nil || !strings.ContainsAny(arg, "*?[") {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("bad path glob %q: %w", arg, err)
+		}
+
+		var dirs []string
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && info.IsDir() {
+				dirs = append(dirs, m)
+			}
+		}
+		if len(dirs) == 0 {
+			return nil, fmt.Errorf("no directories match %q", arg)
+		}
+
+		expanded = append(expanded, dirs...)
+	}
+
+	return expanded, nil
+}
+
 // TODO needed?
 // func listRepos(args []string) ([]string, error) {
 // 	var repos []string
@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bloomberg/go-testgroup"
+)
+
+func Test_List(t *testing.T) {
+	testgroup.RunInParallel(t, &listTests{})
+}
+
+type listTests struct{}
+
+// newRepoTree returns a root with an org of repos in different states: "clean" on main,
+// "changed" with a modified file on "feature/one", "untracked" with an untracked file on
+// "feature/two", and "stashed" with a stash on "bugfix".
+func newRepoTree(t *testgroup.T) (root string) {
+	root = t.TempDir()
+
+	for repo, branch := range map[string]string{
+		"clean":     "main",
+		"changed":   "feature/one",
+		"untracked": "feature/two",
+		"stashed":   "bugfix",
+	} {
+		dir := filepath.Join(root, "org", repo)
+		t.Require.NoError(os.MkdirAll(dir, 0o755))
+		runGit(t, dir, "init", "--quiet", "--initial-branch="+branch)
+		t.Require.NoError(os.WriteFile(filepath.Join(dir, "file"), []byte("one\n"), 0o644))
+		runGit(t, dir, "add", "file")
+		commitEmpty(t, dir, "one")
+	}
+
+	org := filepath.Join(root, "org")
+	t.Require.NoError(os.WriteFile(filepath.Join(org, "changed", "file"), []byte("two\n"), 0o644))
+	t.Require.NoError(os.WriteFile(filepath.Join(org, "untracked", "new"), nil, 0o644))
+	t.Require.NoError(os.WriteFile(filepath.Join(org, "stashed", "file"), []byte("two\n"), 0o644))
+	runGit(t, filepath.Join(org, "stashed"), "-c", "user.name=frond", "-c", "user.email=frond@example.com",
+		"stash", "--quiet")
+
+	return root
+}
+
+// relRepos returns the repos under root/org, relative to the working directory, the way
+// selectRepos reports them.
+func relRepos(t *testgroup.T, root string, names ...string) []string {
+	wd, err := os.Getwd()
+	t.Require.NoError(err)
+
+	var repos []string
+	for _, n := range names {
+		rel, err := filepath.Rel(wd, filepath.Join(root, "org", n))
+		t.Require.NoError(err)
+		repos = append(repos, rel)
+	}
+	return repos
+}
+
+//------------------------------------------------------------------------------
+
+func (*listTests) No_filters_selects_everything(t *testgroup.T) {
+	root := newRepoTree(t)
+
+	opts := repoSelectionOptions{}
+	repos, err := opts.selectRepos([]string{root})
+	t.Require.NoError(err)
+	t.Equal(relRepos(t, root, "changed", "clean", "stashed", "untracked"), repos)
+}
+
+func (*listTests) Status_filters_are_ORed(t *testgroup.T) {
+	root := newRepoTree(t)
+
+	testcases := []struct {
+		opts repoSelectionOptions
+		want []string
+	}{
+		{repoSelectionOptions{Changed: true}, []string{"changed"}},
+		{repoSelectionOptions{Untracked: true}, []string{"untracked"}},
+		{repoSelectionOptions{Stashed: true}, []string{"stashed"}},
+		{repoSelectionOptions{Changed: true, Stashed: true}, []string{"changed", "stashed"}},
+		{repoSelectionOptions{Unmerged: true}, nil},
+	}
+
+	for _, tc := range testcases {
+		repos, err := tc.opts.selectRepos([]string{root})
+		t.Require.NoError(err)
+		t.Equal(relRepos(t, root, tc.want...), repos, "%+v", tc.opts)
+	}
+}
+
+func (*listTests) Branch_is_ANDed_with_status(t *testgroup.T) {
+	root := newRepoTree(t)
+
+	testcases := []struct {
+		opts repoSelectionOptions
+		want []string
+	}{
+		{repoSelectionOptions{Branch: "feature/*"}, []string{"changed", "untracked"}},
+		{repoSelectionOptions{Branch: "main"}, []string{"clean"}},
+		{repoSelectionOptions{Branch: "re:^(main|bugfix)$"}, []string{"clean", "stashed"}},
+		{repoSelectionOptions{Branch: "re:two"}, []string{"untracked"}},
+		{repoSelectionOptions{Branch: "feature/*", Changed: true}, []string{"changed"}},
+		{repoSelectionOptions{Branch: "main", Changed: true}, nil},
+	}
+
+	for _, tc := range testcases {
+		repos, err := tc.opts.selectRepos([]string{root})
+		t.Require.NoError(err)
+		t.Equal(relRepos(t, root, tc.want...), repos, "%+v", tc.opts)
+	}
+}
+
+func (*listTests) Bad_branch_patterns(t *testgroup.T) {
+	_, err := newBranchMatcher("re:(")
+	t.Require.Error(err)
+	t.Contains(err.Error(), `bad --branch regexp "re:("`)
+
+	_, err = newBranchMatcher("feature/[")
+	t.Require.Error(err)
+	t.Contains(err.Error(), `bad --branch glob "feature/["`)
+
+	opts := repoSelectionOptions{Branch: "re:("}
+	_, err = opts.selectRepos([]string{t.TempDir()})
+	t.Error(err)
+}
+
+func (*listTests) No_branch_pattern(t *testgroup.T) {
+	match, err := newBranchMatcher("")
+	t.NoError(err)
+	t.Nil(match)
+}
+
+func (*listTests) Overlapping_paths_are_deduplicated(t *testgroup.T) {
+	root := newRepoTree(t)
+
+	repos, err := findRelativeRepos([]string{
+		root,
+		filepath.Join(root, "org"),
+		filepath.Join(root, "org", "clean"),
+		filepath.Join(root, "org", "c*"),
+	})
+	t.Require.NoError(err)
+	t.Equal(relRepos(t, root, "changed", "clean", "stashed", "untracked"), repos)
+}
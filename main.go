@@ -15,6 +15,7 @@ type rootOptions struct {
 	Status statusOptions `command:"status"`
 	Branch branchOptions `command:"branch"`
 	List   listOptions   `command:"list" alias:"ls"`
+	Do     doOptions     `command:"do"`
 	Sync   sync.Options  `command:"sync" subcommands-optional:"true"`
 }
 
@@ -25,6 +26,10 @@ func main() {
 func run(args []string) int {
 	var opts rootOptions
 
+	if len(args) > 0 && args[0] == "do" {
+		args, opts.Do.command = splitPathsAndCommand(args)
+	}
+
 	parser := flags.NewParser(&opts, flags.Default)
 	_, err := parser.ParseArgs(args)
 	if err != nil {
@@ -0,0 +1,21 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"os"
+
+	"github.com/mikesep/frond/internal/action"
+)
+
+type parallelOptions struct {
+	Jobs      action.Jobs `short:"j" long:"jobs" value-name:"N" description:"Work on up to N repos in parallel. (default: NumCPU)"`
+	KeepGoing bool        `short:"k" long:"keep-going" description:"Keep going even if a repo fails."`
+}
+
+// runActions does the actions in parallel, reporting on each of them as they finish. It
+// returns an error if any of them failed.
+func (opts *parallelOptions) runActions(actions []action.Action, plain bool) error {
+	return action.RunAndReport(os.Stdout, actions, opts.Jobs, opts.KeepGoing, plain)
+}
@@ -5,12 +5,166 @@ package main
 
 import (
 	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mikesep/frond/internal/git"
 )
 
 type statusOptions struct {
+	repoSelectionOptions
+}
+
+type repoStatus struct {
+	git.Status
+	LastFetch time.Time
+	Err       error
 }
 
 func (opts *statusOptions) Execute(args []string) error {
-	fmt.Printf("I'm statusOptions.Execute\nargs=%q\nopts=%+v\n", args, opts)
+	repos, err := opts.selectRepos(args)
+	if err != nil {
+		return err
+	}
+
+	if len(repos) == 0 {
+		return nil
+	}
+
+	statuses := getRepoStatuses(repos)
+
+	branches := make([]string, len(repos))
+	for i, st := range statuses {
+		branches[i] = st.BranchHead
+	}
+
+	maxRepoLen := maxLength(repos)
+	maxBranchLen := maxLength(branches)
+	if maxBranchLen < len("BRANCH") {
+		maxBranchLen = len("BRANCH")
+	}
+
+	now := time.Now()
+
+	fmt.Printf("%-*s  %-*s  %-11s  %s  %s\n",
+		maxRepoLen, "REPO", maxBranchLen, "BRANCH", "UPSTREAM", "FLAGS", "FETCHED")
+
+	failed := 0
+	for i, r := range repos {
+		st := statuses[i]
+		if st.Err != nil {
+			failed++
+			fmt.Printf("%-*s  ERROR: %v\n", maxRepoLen, r, st.Err)
+			continue
+		}
+
+		fmt.Printf("%-*s  %-*s  %-11s  %s  %s\n",
+			maxRepoLen, r,
+			maxBranchLen, st.BranchHead,
+			formatAheadBehind(st.Status),
+			formatFlags(st.Status),
+			formatFetchAge(now, st.LastFetch),
+		)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to get the status of %d repos", failed)
+	}
+
 	return nil
 }
+
+func getRepoStatuses(repos []string) []repoStatus {
+	statuses := make([]repoStatus, len(repos))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.NumCPU())
+
+	for i, r := range repos {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, r string) {
+			defer func() { <-sem }()
+			defer wg.Done()
+
+			st, err := git.GetStatus(r)
+			if err != nil {
+				statuses[i].Err = err
+				return
+			}
+
+			repo := git.LocalRepo{Root: r}
+			lastFetch, err := repo.LastFetchTime()
+			if err != nil {
+				statuses[i].Err = err
+				return
+			}
+
+			statuses[i] = repoStatus{Status: st, LastFetch: lastFetch}
+		}(i, r)
+	}
+
+	wg.Wait()
+
+	return statuses
+}
+
+// formatAheadBehind returns "=" when in sync, "+A -B" when not, and "-" without an upstream.
+func formatAheadBehind(st git.Status) string {
+	switch {
+	case st.BranchUpstream == "":
+		return "-"
+	case st.UpstreamGone:
+		return "gone"
+	case st.Ahead == 0 && st.Behind == 0:
+		return "="
+	}
+
+	var parts []string
+	if st.Ahead > 0 {
+		parts = append(parts, fmt.Sprintf("+%d", st.Ahead))
+	}
+	if st.Behind > 0 {
+		parts = append(parts, fmt.Sprintf("-%d", st.Behind))
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatFlags returns a fixed-width string like "cU?s" with a space for each unset flag.
+func formatFlags(st git.Status) string {
+	flag := func(set bool, c byte) byte {
+		if set {
+			return c
+		}
+		return ' '
+	}
+
+	return string([]byte{
+		flag(st.ChangedOrRenamed, 'c'),
+		flag(st.Unmerged, 'U'),
+		flag(st.Untracked, '?'),
+		flag(st.Stashed, 's'),
+		' ', // pad to the width of the FLAGS header
+	})
+}
+
+func formatFetchAge(now, lastFetch time.Time) string {
+	if lastFetch.IsZero() {
+		return "never"
+	}
+
+	age := now.Sub(lastFetch)
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(age.Hours()/24))
+	}
+}
@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: 2021 Michael Seplowitz
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bloomberg/go-testgroup"
+	"github.com/mikesep/frond/internal/git"
+)
+
+func Test_Status(t *testing.T) {
+	testgroup.RunInParallel(t, &statusTests{})
+}
+
+type statusTests struct{}
+
+func (*statusTests) Format_ahead_behind(t *testgroup.T) {
+	testcases := []struct {
+		status git.Status
+		want   string
+	}{
+		{git.Status{}, "-"},
+		{git.Status{BranchUpstream: "origin/main"}, "="},
+		{git.Status{BranchUpstream: "origin/main", Ahead: 2}, "+2"},
+		{git.Status{BranchUpstream: "origin/main", Behind: 3}, "-3"},
+		{git.Status{BranchUpstream: "origin/main", Ahead: 2, Behind: 3}, "+2 -3"},
+		{git.Status{BranchUpstream: "origin/main", UpstreamGone: true}, "gone"},
+	}
+
+	for _, tc := range testcases {
+		t.Equal(tc.want, formatAheadBehind(tc.status), "%+v", tc.status)
+	}
+}
+
+func (*statusTests) Format_flags(t *testgroup.T) {
+	testcases := []struct {
+		status git.Status
+		want   string
+	}{
+		{git.Status{}, "     "},
+		{git.Status{ChangedOrRenamed: true}, "c    "},
+		{git.Status{Unmerged: true}, " U   "},
+		{git.Status{Untracked: true}, "  ?  "},
+		{git.Status{Stashed: true}, "   s "},
+		{git.Status{Ignored: true}, "     "},
+		{git.Status{ChangedOrRenamed: true, Unmerged: true, Untracked: true, Stashed: true}, "cU?s "},
+	}
+
+	for _, tc := range testcases {
+		got := formatFlags(tc.status)
+		t.Equal(tc.want, got, "%+v", tc.status)
+		t.Len(got, len("FLAGS"))
+	}
+}
+
+func (*statusTests) Format_fetch_age(t *testgroup.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	testcases := []struct {
+		lastFetch time.Time
+		want      string
+	}{
+		{time.Time{}, "never"},
+		{now.Add(-30 * time.Second), "just now"},
+		{now.Add(-time.Minute), "1m ago"},
+		{now.Add(-59 * time.Minute), "59m ago"},
+		{now.Add(-time.Hour), "1h ago"},
+		{now.Add(-23*time.Hour - 59*time.Minute), "23h ago"},
+		{now.Add(-24 * time.Hour), "1d ago"},
+		{now.Add(-400 * 24 * time.Hour), "400d ago"},
+	}
+
+	for _, tc := range testcases {
+		t.Equal(tc.want, formatFetchAge(now, tc.lastFetch), "%v", now.Sub(tc.lastFetch))
+	}
+}
+
+func (*statusTests) Expand_org_globs(t *testgroup.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"org-a", "org-b", "other", "org[literal]"} {
+		t.Require.NoError(os.Mkdir(filepath.Join(root, dir), 0o755))
+	}
+	t.Require.NoError(os.WriteFile(filepath.Join(root, "org-file"), nil, 0o644))
+
+	in := func(names ...string) []string {
+		paths := make([]string, len(names))
+		for i, n := range names {
+			paths[i] = filepath.Join(root, n)
+		}
+		return paths
+	}
+
+	expanded, err := expandPathGlobs(in("org-*", "other"))
+	t.Require.NoError(err)
+	t.Equal(in("org-a", "org-b", "other"), expanded)
+
+	expanded, err = expandPathGlobs(in("org[literal]"))
+	t.Require.NoError(err)
+	t.Equal(in("org[literal]"), expanded, "existing paths aren't globs")
+
+	expanded, err = expandPathGlobs(in("missing"))
+	t.Require.NoError(err)
+	t.Equal(in("missing"), expanded, "not a glob")
+
+	_, err = expandPathGlobs(in("nope-*"))
+	t.EqualError(err, `no directories match "`+filepath.Join(root, "nope-*")+`"`)
+}